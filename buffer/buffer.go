@@ -136,6 +136,38 @@ func (b *Buffer) EditedIndices() []int64 {
 	return eis
 }
 
+// ReplacedIndices returns the indices of edited regions if the buffer only
+// replaces the bytes of the reader, so that the regions can be written back
+// to the reader in place. The bool is false if the other regions are shifted
+// by insertions or deletions, or read from other readers.
+func (b *Buffer) ReplacedIndices(r io.Seeker) ([]int64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, false
+	}
+	if l, err := b.len(); err != nil || l != size {
+		return nil, false
+	}
+	eis := make([]int64, 0, len(b.rrs))
+	for _, rr := range b.rrs {
+		switch rr.r.(type) {
+		case *bytesReader:
+			if rr.max > size {
+				return nil, false
+			}
+			eis = append(eis, rr.min)
+			eis = append(eis, rr.max)
+		default:
+			if rr.r != r || rr.diff != 0 {
+				return nil, false
+			}
+		}
+	}
+	return eis, true
+}
+
 // Clone the buffer.
 func (b *Buffer) Clone() *Buffer {
 	b.mu.Lock()
@@ -266,6 +298,94 @@ func (b *Buffer) Delete(offset int64) {
 	panic("buffer.Buffer.Delete: unreachable")
 }
 
+// InsertBytes inserts the bytes at the specific position.
+func (b *Buffer) InsertBytes(offset int64, bs []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.insertBytes(offset, bs)
+	b.cleanup()
+}
+
+// ReplaceBytes replaces the bytes at the specific position.
+// The buffer is extended when the bytes exceed the end.
+func (b *Buffer) ReplaceBytes(offset int64, bs []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.deleteBytes(offset, int64(len(bs)))
+	b.insertBytes(offset, bs)
+	b.cleanup()
+}
+
+// DeleteBytes deletes the bytes of the count at the specific position.
+func (b *Buffer) DeleteBytes(offset, count int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.deleteBytes(offset, count)
+	b.cleanup()
+}
+
+func (b *Buffer) insertBytes(offset int64, bs []byte) {
+	n := int64(len(bs))
+	if n == 0 {
+		return
+	}
+	i := b.split(offset)
+	b.rrs = append(b.rrs, readerRange{})
+	copy(b.rrs[i+1:], b.rrs[i:])
+	b.rrs[i] = readerRange{newBytesReader(append([]byte(nil), bs...)), offset, offset + n, -offset}
+	for i++; i < len(b.rrs); i++ {
+		b.rrs[i].min += n
+		b.rrs[i].max = mathutil.MinInt64(b.rrs[i].max, math.MaxInt64-n) + n
+		b.rrs[i].diff -= n
+	}
+}
+
+func (b *Buffer) deleteBytes(offset, count int64) {
+	if l, err := b.len(); err == nil {
+		count = mathutil.MinInt64(count, l-offset)
+	}
+	if count <= 0 {
+		return
+	}
+	i, j := b.split(offset), b.split(offset+count)
+	b.rrs = append(b.rrs[:i], b.rrs[j:]...)
+	for ; i < len(b.rrs); i++ {
+		b.rrs[i].min -= count
+		if b.rrs[i].max != math.MaxInt64 {
+			b.rrs[i].max -= count
+		}
+		b.rrs[i].diff += count
+	}
+}
+
+// split splits the range at the position, and returns the index of the
+// range starting at the position. The bytes of a bytes reader are split
+// without copying, and the former is capped so that appending to it does
+// not overwrite the latter.
+func (b *Buffer) split(offset int64) int {
+	for i, rr := range b.rrs {
+		if offset >= rr.max {
+			continue
+		}
+		if offset == rr.min {
+			return i
+		}
+		b.rrs = append(b.rrs, readerRange{})
+		copy(b.rrs[i+1:], b.rrs[i:])
+		b.rrs[i].max = offset
+		switch r := rr.r.(type) {
+		case *bytesReader:
+			k := offset + rr.diff
+			b.rrs[i].r = newBytesReader(r.bs[:k:k])
+			b.rrs[i+1] = readerRange{newBytesReader(r.bs[k:]), offset, rr.max, -offset}
+		default:
+			b.rrs[i+1] = readerRange{rr.r, offset, rr.max, rr.diff}
+		}
+		return i + 1
+	}
+	panic("buffer.Buffer.split: unreachable")
+}
+
 func (b *Buffer) clone(r readAtSeeker) readAtSeeker {
 	switch br := r.(type) {
 	case *bytesReader:
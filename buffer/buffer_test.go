@@ -378,3 +378,109 @@ func TestBufferDelete(t *testing.T) {
 		t.Errorf("len(b.rrs) should be 4 but got: %d", len(b.rrs))
 	}
 }
+
+func TestBufferReplacedIndices(t *testing.T) {
+	r := strings.NewReader("0123456789abcdef")
+	b := NewBuffer(r)
+	if eis, ok := b.ReplacedIndices(r); !ok || len(eis) != 0 {
+		t.Errorf("replaced indices should be empty but got: %v, %v", eis, ok)
+	}
+
+	b.Replace(2, 0x31)
+	b.Replace(3, 0x31)
+	b.Replace(15, 0x31)
+	eis, ok := b.ReplacedIndices(r)
+	expected := []int64{2, 4, 15, 16}
+	if !ok || !reflect.DeepEqual(eis, expected) {
+		t.Errorf("replaced indices should be %v but got: %v, %v", expected, eis, ok)
+	}
+
+	if _, ok := b.ReplacedIndices(strings.NewReader("0123456789abcdef")); ok {
+		t.Errorf("replaced indices should not be found for other readers")
+	}
+
+	c := b.Clone()
+	c.Insert(5, 0x31)
+	c.Delete(8)
+	if _, ok := c.ReplacedIndices(r); ok {
+		t.Errorf("replaced indices should not be found after insertion and deletion")
+	}
+
+	b.Replace(16, 0x31)
+	if _, ok := b.ReplacedIndices(r); ok {
+		t.Errorf("replaced indices should not be found after extending the buffer")
+	}
+}
+
+func TestBufferBytes(t *testing.T) {
+	r := strings.NewReader("0123456789abcdef")
+	b := NewBuffer(r)
+
+	tests := []struct {
+		op       string
+		index    int64
+		bs       string
+		expected string
+	}{
+		{"replace", 2, "xyz", "01xyz56789abcdef"},
+		{"insert", 4, "XYZ", "01xyXYZz56789abcdef"},
+		{"delete", 3, "....", "01xz56789abcdef"},
+		{"Insert", 3, "!", "01x!z56789abcdef"},
+		{"Replace", 2, "?", "01?!z56789abcdef"},
+		{"insert", 16, "gh", "01?!z56789abcdefgh"},
+		{"delete", 7, "", "01?!z56789abcdefgh"},
+		{"delete", 0, "..", "?!z56789abcdefgh"},
+		{"replace", 14, "GHI", "?!z56789abcdefGHI"},
+		{"delete", 13, "........", "?!z56789abcde"},
+		{"insert", 0, "AB", "AB?!z56789abcde"},
+		{"Delete", 2, "", "AB!z56789abcde"},
+		{"replace", 0, "0123456789abcdef", "0123456789abcdef"},
+	}
+
+	for _, test := range tests {
+		switch test.op {
+		case "insert":
+			b.InsertBytes(test.index, []byte(test.bs))
+		case "replace":
+			b.ReplaceBytes(test.index, []byte(test.bs))
+		case "delete":
+			b.DeleteBytes(test.index, int64(len(test.bs)))
+		case "Insert":
+			b.Insert(test.index, test.bs[0])
+		case "Replace":
+			b.Replace(test.index, test.bs[0])
+		case "Delete":
+			b.Delete(test.index)
+		}
+
+		l, err := b.Len()
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if l != int64(len(test.expected)) {
+			t.Errorf("l should be %d but got: %d", len(test.expected), l)
+		}
+
+		p := make([]byte, l+8)
+		n, err := b.ReadAt(p, 0)
+		if err != nil && err != io.EOF {
+			t.Errorf("err should be nil or io.EOF but got: %v", err)
+		}
+		if string(p[:n]) != test.expected {
+			t.Errorf("p should be %s but got: %s", test.expected, string(p[:n]))
+		}
+	}
+
+	if len(b.rrs) != 2 {
+		t.Errorf("len(b.rrs) should be 2 but got: %d", len(b.rrs))
+	}
+
+	c := NewBuffer(r)
+	c.ReplaceBytes(2, []byte("xyz"))
+	c.ReplaceBytes(10, []byte("XYZ"))
+	eis, ok := c.ReplacedIndices(r)
+	expected := []int64{2, 5, 10, 13}
+	if !ok || !reflect.DeepEqual(eis, expected) {
+		t.Errorf("replaced indices should be %v but got: %v, %v", expected, eis, ok)
+	}
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
 
 	"github.com/itchyny/bed/cmdline"
 	"github.com/itchyny/bed/editor"
@@ -10,20 +14,127 @@ import (
 	"github.com/itchyny/bed/window"
 )
 
+const usage = `Usage: %[1]s [options] [file ...]
+       %[1]s [options] -   (read from the standard input)
+
+Options:
+  +offset      start at the offset (at the end with +)
+  -c command   execute the command after loading the files
+  -d           open two files in diff mode
+  -o           open the files in horizontal splits
+  -O           open the files in vertical splits
+  -p           open the files in tab pages
+  -r           recover the files from the swap files (list them without files)
+               or from the given swap files (.file.bed.swo)
+  -R           open the files in read-only mode
+  -u rcfile    use the rcfile instead of ~/.bedrc (NONE to skip)
+  --help       show this help message
+  --version    show the version
+`
+
+const defaultRCFile = "~/.bedrc"
+
 func run(args []string) int {
-	if len(args) > 2 {
-		fmt.Fprintf(os.Stderr, "%s: too many files\n", name)
+	var filenames, commands []string
+	var diff, recovery, readonly bool
+	var offset string
+	layout := editor.LayoutBuffers
+	rcfile := defaultRCFile
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			filenames = append(filenames, args[i+1:]...)
+			i = len(args)
+		case arg == "--help":
+			fmt.Printf(usage, name)
+			return 0
+		case arg == "--version":
+			fmt.Printf("%s %s\n", name, version)
+			return 0
+		case arg == "-c" || arg == "-u":
+			if i++; i == len(args) {
+				fmt.Fprintf(os.Stderr, "%s: argument missing after %s\n", name, arg)
+				return 1
+			}
+			if arg == "-c" {
+				commands = append(commands, args[i])
+			} else {
+				rcfile = args[i]
+			}
+		case arg == "-d":
+			diff = true
+		case arg == "-o":
+			layout = editor.LayoutHorizontal
+		case arg == "-O":
+			layout = editor.LayoutVertical
+		case arg == "-p":
+			layout = editor.LayoutTabs
+		case arg == "-r":
+			recovery = true
+		case arg == "-R":
+			readonly = true
+		case strings.HasPrefix(arg, "+"):
+			if offset = arg[1:]; offset == "" {
+				offset = "$"
+			}
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			fmt.Fprintf(os.Stderr, "%s: unknown option: %s\n", name, arg)
+			return 1
+		default:
+			filenames = append(filenames, arg)
+		}
+	}
+	if diff && len(filenames) != 2 {
+		fmt.Fprintf(os.Stderr, "%s: diff mode requires two files\n", name)
+		return 1
+	}
+	var readStdin bool
+	for _, filename := range filenames {
+		readStdin = readStdin || filename == "-"
+	}
+	rcCommands, err := readRCFile(rcfile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
 		return 1
 	}
-	editor := editor.NewEditor(
-		tui.NewTui(), window.NewManager(), cmdline.NewCmdline(),
-	)
+	wm := window.NewManager()
+	wm.SetReadOnly(readonly)
+	if recovery {
+		if len(filenames) == 0 {
+			if err := listSwapFiles(os.Stdout); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+				return 1
+			}
+			return 0
+		}
+		for i, filename := range filenames {
+			if filenames[i], err = wm.Recover(filename); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+				return 1
+			}
+		}
+	} else if !readStdin {
+		// ask on the terminal unless the standard input is read
+		if ok, err := checkSwapFiles(wm, filenames); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			return 1
+		} else if !ok {
+			return 0
+		}
+	}
+	editor := editor.NewEditor(tui.NewTui(), wm, cmdline.NewCmdline())
 	if err := editor.Init(); err != nil {
 		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
 		return 1
 	}
-	if len(args) > 1 {
-		if err := editor.Open(args[1]); err != nil {
+	if diff {
+		if err := editor.OpenDiff(filenames); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			return 1
+		}
+	} else if len(filenames) > 0 {
+		if err := editor.OpenFiles(filenames, layout); err != nil {
 			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
 			return 1
 		}
@@ -33,6 +144,22 @@ func run(args []string) int {
 			return 1
 		}
 	}
+	if readStdin {
+		// the standard input is read on opening, reopen the terminal
+		if err := reopenTTY(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			return 1
+		}
+	}
+	for _, cmd := range rcCommands {
+		editor.Execute(cmd)
+	}
+	if offset != "" {
+		editor.Execute("goto " + offset)
+	}
+	for _, cmd := range commands {
+		editor.Execute(cmd)
+	}
 	if err := editor.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
 		return 1
@@ -43,3 +170,32 @@ func run(args []string) int {
 	}
 	return 0
 }
+
+// readRCFile reads the commands in the rcfile. Empty lines and the lines
+// starting with a double quote are skipped. The default rcfile is optional.
+func readRCFile(rcfile string) ([]string, error) {
+	if rcfile == "NONE" {
+		return nil, nil
+	}
+	filename, err := homedir.Expand(rcfile)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) && rcfile == defaultRCFile {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	var commands []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, `"`) {
+			commands = append(commands, line)
+		}
+	}
+	return commands, scanner.Err()
+}
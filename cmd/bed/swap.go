@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+
+	"github.com/itchyny/bed/window"
+)
+
+// listSwapFiles prints the swap files in the current directory (bed -r).
+func listSwapFiles(w io.Writer) error {
+	names, err := filepath.Glob(".*.bed.sw[a-p]")
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		fmt.Fprintln(w, "no swap files found")
+	}
+	for _, name := range names {
+		fmt.Fprintln(w, name)
+	}
+	return nil
+}
+
+// checkSwapFiles asks what to do with the swap files of the files, which are
+// left by crashes or the other sessions. It returns false to quit.
+func checkSwapFiles(wm *window.Manager, filenames []string) (bool, error) {
+	stdin := bufio.NewReader(os.Stdin)
+	for _, filename := range filenames {
+		name, err := homedir.Expand(filename)
+		if err != nil {
+			return false, err
+		}
+		swapFile := window.SwapFileName(name)
+		if _, err := os.Stat(swapFile); err != nil {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%s: found a swap file %s\n", filename, swapFile)
+	ask:
+		for {
+			fmt.Fprint(os.Stderr, "(R)ecover, (D)elete it, (E)dit anyway, (Q)uit: ")
+			line, err := stdin.ReadString('\n')
+			if err != nil && line == "" {
+				if err == io.EOF {
+					return false, nil
+				}
+				return false, err
+			}
+			switch strings.ToLower(strings.TrimSpace(line)) {
+			case "r":
+				if _, err := wm.Recover(filename); err != nil {
+					return false, err
+				}
+				break ask
+			case "d":
+				if err := os.Remove(swapFile); err != nil {
+					return false, err
+				}
+				break ask
+			case "e":
+				break ask
+			case "q":
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
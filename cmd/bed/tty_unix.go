@@ -0,0 +1,16 @@
+// +build !windows
+
+package main
+
+import "os"
+
+// reopenTTY replaces the standard input with the terminal
+// so that the user interface reads the keys from the terminal.
+func reopenTTY() error {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return err
+	}
+	os.Stdin = tty
+	return nil
+}
@@ -0,0 +1,9 @@
+// +build windows
+
+package main
+
+// reopenTTY does nothing because the console input is opened on initializing
+// the user interface.
+func reopenTTY() error {
+	return nil
+}
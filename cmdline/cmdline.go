@@ -1,6 +1,8 @@
 package cmdline
 
 import (
+	"fmt"
+	"strings"
 	"sync"
 	"unicode"
 
@@ -82,6 +84,10 @@ func (c *Cmdline) Run() {
 			c.mu.Unlock()
 			continue
 		case event.ExecuteCmdline:
+			if e.Arg != "" {
+				c.typ = ':'
+				c.start(e.Arg)
+			}
 			c.execute()
 		default:
 			c.mu.Unlock()
@@ -166,7 +172,7 @@ func (c *Cmdline) insert(ch rune) {
 }
 
 func (c *Cmdline) complete(forward bool) {
-	cmd, _, prefix, arg, err := parse(c.cmdline)
+	cmd, _, _, prefix, arg, err := parse(c.cmdline)
 	if err != nil {
 		c.completor.clear()
 		return
@@ -179,13 +185,13 @@ func (c *Cmdline) complete(forward bool) {
 func (c *Cmdline) execute() {
 	switch c.typ {
 	case ':':
-		cmd, r, _, arg, err := parse(c.cmdline)
+		e, err := parseCommand(c.cmdline)
 		if err != nil {
 			c.eventCh <- event.Event{Type: event.Error, Error: err}
 			return
 		}
-		if cmd.name != "" {
-			c.eventCh <- event.Event{Type: cmd.eventType, Range: r, CmdName: cmd.name, Arg: arg}
+		if e.Type != event.Nop {
+			c.eventCh <- e
 		}
 	case '/':
 		c.eventCh <- event.Event{Type: event.ExecuteSearch, Arg: string(c.cmdline), Rune: '/'}
@@ -196,6 +202,39 @@ func (c *Cmdline) execute() {
 	}
 }
 
+// Parse the command to the event.
+func (c *Cmdline) Parse(cmdline string) (event.Event, error) {
+	return parseCommand([]rune(cmdline))
+}
+
+func parseCommand(cmdline []rune) (event.Event, error) {
+	cmd, r, bang, _, arg, err := parse(cmdline)
+	if err != nil {
+		return event.Event{}, err
+	}
+	if cmd.eventType == event.CursorGoto && r == nil && arg != "" {
+		var i int
+		if r, i, err = event.ParseRange([]rune(arg), 0); err != nil {
+			return event.Event{}, err
+		} else if r == nil || i < len([]rune(arg)) {
+			return event.Event{}, fmt.Errorf("invalid argument for %s: %s", cmd.name, arg)
+		}
+		arg = ""
+	}
+	if cmd.eventType == event.Vertical {
+		if cmd, r, bang, _, arg, err = parseVertical(arg); err != nil {
+			return event.Event{}, err
+		}
+	}
+	if cmd.name == "" {
+		return event.Event{}, nil
+	}
+	if cmd.eventType == event.Write && strings.HasPrefix(arg, "!") {
+		cmd.eventType, arg = event.WriteCommand, strings.TrimSpace(arg[1:])
+	}
+	return event.Event{Type: cmd.eventType, Range: r, CmdName: cmd.name, Arg: arg, Bang: bang}, nil
+}
+
 // Get returns the current state of cmdline.
 func (c *Cmdline) Get() ([]rune, int, []string, int) {
 	c.mu.Lock()
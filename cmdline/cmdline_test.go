@@ -351,6 +351,8 @@ func TestCmdlineExecuteWrite(t *testing.T) {
 		{"w", "w[rite]"},
 		{" :   : write  sample.txt", "w[rite]"},
 		{"'<,'>write sample.txt", "w[rite]"},
+		{"'a,'bw sample.txt", "w[rite]"},
+		{"/MZ/,/PE/-1w header.bin", "w[rite]"},
 	} {
 		c.clear()
 		c.cmdline = []rune(cmd.cmd)
@@ -411,6 +413,8 @@ func TestCmdlineExecuteGoto(t *testing.T) {
 		{"  0xfedcba  ", event.Absolute{0xfedcba}, event.CursorGoto},
 		{"  +0x44ef ", event.Relative{0x44ef}, event.CursorGoto},
 		{"  -0xff ", event.Relative{-0xff}, event.CursorGoto},
+		{"  :0x200+3*0x40", event.Absolute{Offset: 0x2c0}, event.CursorGoto},
+		{"  :(0x3C << 2) % 0XFF ", event.Absolute{Offset: 0xf0}, event.CursorGoto},
 	} {
 		c.clear()
 		c.cmdline = []rune(cmd.cmd)
@@ -429,6 +433,144 @@ func TestCmdlineExecuteGoto(t *testing.T) {
 	}
 }
 
+func TestCmdlineExecuteEcho(t *testing.T) {
+	c := NewCmdline()
+	ch := make(chan event.Event, 1)
+	c.Init(ch, make(chan event.Event), make(chan struct{}))
+	for _, cmd := range []struct {
+		cmd  string
+		name string
+		typ  event.Type
+		arg  string
+	}{
+		{"echo u32le(0x3c)", "ec[ho]", event.Echo, "u32le(0x3c)"},
+		{"ec 1+2", "ec[ho]", event.Echo, "1+2"},
+		{"eval len()", "ev[al]", event.Echo, "len()"},
+		{`exe "goto " . u32le(0x3c)`, "exe[cute]", event.Execute, `"goto " . u32le(0x3c)`},
+	} {
+		c.clear()
+		c.cmdline = []rune(cmd.cmd)
+		c.typ = ':'
+		c.execute()
+		e := <-ch
+		if e.CmdName != cmd.name {
+			t.Errorf("cmdline should report command name %q but got %q", cmd.name, e.CmdName)
+		}
+		if e.Type != cmd.typ {
+			t.Errorf("cmdline should emit %d but got %d with %q", cmd.typ, e.Type, cmd.cmd)
+		}
+		if e.Arg != cmd.arg {
+			t.Errorf("cmdline should emit event with arg %q but got %q", cmd.arg, e.Arg)
+		}
+	}
+}
+
+func TestCmdlineExecuteVertical(t *testing.T) {
+	c := NewCmdline()
+	ch := make(chan event.Event, 1)
+	c.Init(ch, make(chan event.Event), make(chan struct{}))
+	for _, cmd := range []struct {
+		cmd  string
+		name string
+		typ  event.Type
+		arg  string
+	}{
+		{"resize 10", "res[ize]", event.Resize, "10"},
+		{"vertical resize +5", "res[ize]", event.VerticalResize, "+5"},
+		{"vert res", "res[ize]", event.VerticalResize, ""},
+		{"vertical new foo", "new", event.Vnew, "foo"},
+		{"vert sp", "sp[lit]", event.Vsplit, ""},
+		{"vertical write", "", event.Error, ""},
+	} {
+		c.clear()
+		c.cmdline = []rune(cmd.cmd)
+		c.typ = ':'
+		c.execute()
+		e := <-ch
+		if e.CmdName != cmd.name {
+			t.Errorf("cmdline should report command name %q but got %q", cmd.name, e.CmdName)
+		}
+		if e.Type != cmd.typ {
+			t.Errorf("cmdline should emit %d but got %d with %q", cmd.typ, e.Type, cmd.cmd)
+		}
+		if e.Arg != cmd.arg {
+			t.Errorf("cmdline should emit event with arg %q but got %q", cmd.arg, e.Arg)
+		}
+	}
+}
+
+func TestCmdlineParse(t *testing.T) {
+	c := NewCmdline()
+	for _, cmd := range []struct {
+		cmd  string
+		typ  event.Type
+		name string
+		bang bool
+		err  string
+	}{
+		{"", event.Nop, "", false, ""},
+		{":set jf=u8", event.Set, "se[t]", false, ""},
+		{"goto 0x100", event.CursorGoto, "go[to]", false, ""},
+		{"w! foo", event.Write, "w[rite]", true, ""},
+		{"q!", event.Quit, "q[uit]", true, ""},
+		{"view foo", event.View, "vie[w]", false, ""},
+		{"'<,'>w !xxd", event.WriteCommand, "w[rite]", false, ""},
+		{"'<,'>!gzip -d", event.Filter, "!", false, ""},
+		{"r !ls", event.Read, "r[ead]", false, ""},
+		{"vert new", event.Vnew, "new", false, ""},
+		{"foo", event.Nop, "", false, "unknown command: foo"},
+		{"goto foo", event.Nop, "", false, "invalid argument for go[to]: foo"},
+		{"0x", event.Nop, "", false, "invalid number: 0x"},
+		{"10/0", event.Nop, "", false, "division by zero"},
+		{"8%0", event.Nop, "", false, "division by zero"},
+		{"1<<64", event.Nop, "", false, "invalid shift count: 64"},
+		{"goto 10/0", event.Nop, "", false, "division by zero"},
+	} {
+		e, err := c.Parse(cmd.cmd)
+		if e.Type != cmd.typ {
+			t.Errorf("cmdline should parse %q to %d but got %d", cmd.cmd, cmd.typ, e.Type)
+		}
+		if e.CmdName != cmd.name {
+			t.Errorf("cmdline should report command name %q but got %q", cmd.name, e.CmdName)
+		}
+		if e.Bang != cmd.bang {
+			t.Errorf("cmdline should parse %q with bang %v but got %v", cmd.cmd, cmd.bang, e.Bang)
+		}
+		if cmd.err == "" && err != nil || cmd.err != "" && (err == nil || err.Error() != cmd.err) {
+			t.Errorf("cmdline should report error %q but got %v", cmd.err, err)
+		}
+	}
+}
+
+func TestCmdlineExecuteArg(t *testing.T) {
+	c := NewCmdline()
+	eventCh, cmdlineCh, redrawCh := make(chan event.Event), make(chan event.Event), make(chan struct{})
+	c.Init(eventCh, cmdlineCh, redrawCh)
+	defer func() {
+		close(eventCh)
+		close(cmdlineCh)
+		close(redrawCh)
+	}()
+	go c.Run()
+	go func() {
+		cmdlineCh <- event.Event{Type: event.ExecuteCmdline, Arg: "goto 0x1000+60"}
+		cmdlineCh <- event.Event{Type: event.ExecuteCmdline, Arg: "goto foo"}
+	}()
+	e := <-eventCh
+	<-redrawCh
+	if e.Type != event.CursorGoto {
+		t.Errorf("cmdline should emit CursorGoto event but got %v", e.Type)
+	}
+	if expected := (event.Absolute{Offset: 0x1000 + 60}); !reflect.DeepEqual(e.Range.From, expected) {
+		t.Errorf("cmdline should report command with position %#v but got %#v", expected, e.Range.From)
+	}
+	e = <-eventCh
+	<-redrawCh
+	if e.Type != event.Error {
+		t.Errorf("cmdline should emit Error event but got %v", e.Type)
+	}
+}
+
 func TestCmdlineComplete(t *testing.T) {
 	c := NewCmdline()
 	c.completor = newCompletor(&mockFilesystem{})
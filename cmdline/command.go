@@ -9,10 +9,43 @@ type command struct {
 
 var commands = []command{
 	{"e[dit]", event.Edit},
+	{"vie[w]", event.View},
 	{"new", event.New},
 	{"vne[w]", event.Vnew},
+	{"sp[lit]", event.Split},
+	{"vs[plit]", event.Vsplit},
+	{"clo[se]", event.Close},
+	{"on[ly]", event.Only},
+	{"res[ize]", event.Resize},
+	{"vert[ical]", event.Vertical},
 	{"winc[md]", event.Wincmd},
 
+	{"ls", event.Buffers},
+	{"buffers", event.Buffers},
+	{"files", event.Buffers},
+	{"b[uffer]", event.Buffer},
+	{"bn[ext]", event.BufferNext},
+	{"bp[revious]", event.BufferPrev},
+	{"bN[ext]", event.BufferPrev},
+	{"bd[elete]", event.BufferDelete},
+	{"tabnew", event.TabNew},
+	{"tabe[dit]", event.TabNew},
+	{"tabc[lose]", event.TabClose},
+	{"tabn[ext]", event.TabNext},
+	{"tabp[revious]", event.TabPrev},
+	{"tabN[ext]", event.TabPrev},
+	{"difft[his]", event.DiffThis},
+	{"diffo[ff]", event.DiffOff},
+	{"diffg[et]", event.DiffGet},
+	{"diffpu[t]", event.DiffPut},
+
+	{"marks", event.Marks},
+	{"se[t]", event.Set},
+	{"go[to]", event.CursorGoto},
+	{"ec[ho]", event.Echo},
+	{"ev[al]", event.Echo},
+	{"exe[cute]", event.Execute},
+
 	{"u[ndo]", event.Undo},
 	{"red[o]", event.Redo},
 
@@ -21,6 +54,7 @@ var commands = []command{
 	{"qa[ll]", event.QuitAll},
 	{"quita[ll]", event.QuitAll},
 	{"w[rite]", event.Write},
+	{"r[ead]", event.Read},
 	{"wq", event.WriteQuit},
 	{"x[it]", event.WriteQuit},
 	{"xa[ll]", event.WriteQuit},
@@ -32,7 +32,7 @@ func (c *completor) clear() {
 
 func (c *completor) complete(cmdline string, cmd command, prefix string, arg string, forward bool) string {
 	switch cmd.eventType {
-	case event.Edit, event.New, event.Vnew, event.Write:
+	case event.Edit, event.View, event.New, event.Vnew, event.Split, event.Vsplit, event.TabNew, event.Write, event.Read:
 		return c.completeFilepaths(cmdline, prefix, arg, forward)
 	case event.Wincmd:
 		return c.completeWincmd(cmdline, prefix, arg, forward)
@@ -210,7 +210,7 @@ func (c *completor) completeWincmd(cmdline string, prefix string, arg string, fo
 		return cmdline
 	}
 	c.target = cmdline
-	c.results = []string{"n", "h", "l", "k", "j", "H", "L", "K", "J", "t", "b", "p"}
+	c.results = []string{"n", "h", "l", "k", "j", "H", "L", "K", "J", "t", "b", "p", "s", "v", "c", "o", "q", "+", "-", "<", ">", "=", "_", "|", "x", "r", "R"}
 	c.index = -1
 	return cmdline
 }
@@ -8,15 +8,25 @@ import (
 	"github.com/itchyny/bed/event"
 )
 
-func parse(cmdline []rune) (command, *event.Range, string, string, error) {
+func parse(cmdline []rune) (command, *event.Range, bool, string, string, error) {
 	i, l := 0, len(cmdline)
 	for i < l && (unicode.IsSpace(cmdline[i]) || cmdline[i] == ':') {
 		i++
 	}
 	if i == l {
-		return command{}, nil, "", "", nil
+		return command{}, nil, false, "", "", nil
+	}
+	r, i, err := event.ParseRange(cmdline, i)
+	if err != nil {
+		return command{}, nil, false, "", "", err
+	}
+	if i < l && cmdline[i] == '!' {
+		k := i + 1
+		for k < l && unicode.IsSpace(cmdline[k]) {
+			k++
+		}
+		return command{"!", event.Filter}, r, false, string(cmdline[:k]), strings.TrimSpace(string(cmdline[k:])), nil
 	}
-	r, i := event.ParseRange(cmdline, i)
 	j := i
 	for j < l && !unicode.IsSpace(cmdline[j]) {
 		j++
@@ -26,20 +36,44 @@ func parse(cmdline []rune) (command, *event.Range, string, string, error) {
 		k++
 	}
 	cmdName := string(cmdline[i:j])
+	bang := len(cmdName) > 1 && strings.HasSuffix(cmdName, "!")
+	if bang {
+		cmdName = cmdName[:len(cmdName)-1]
+	}
 	for _, cmd := range commands {
 		if len(cmdName) == 0 || cmdName[0] != cmd.name[0] {
 			continue
 		}
 		for _, c := range expand(cmd.name) {
 			if cmdName == c {
-				return cmd, r, string(cmdline[:k]), strings.TrimSpace(string(cmdline[k:])), nil
+				return cmd, r, bang, string(cmdline[:k]), strings.TrimSpace(string(cmdline[k:])), nil
 			}
 		}
 	}
-	if len(strings.Fields(string(cmdline[k:]))) == 0 && r != nil {
-		return command{"goto", event.CursorGoto}, r, string(cmdline[:k]), "", nil
+	if len(strings.Fields(string(cmdline[k:]))) == 0 && r != nil && !bang {
+		return command{"goto", event.CursorGoto}, r, false, string(cmdline[:k]), "", nil
+	}
+	return command{}, nil, false, "", "", fmt.Errorf("unknown command: %s", string(cmdline))
+}
+
+// parseVertical parses the command after :vertical and
+// returns the vertical variant of the command.
+func parseVertical(arg string) (command, *event.Range, bool, string, string, error) {
+	cmd, r, bang, _, arg, err := parse([]rune(arg))
+	if err != nil {
+		return cmd, r, bang, "", arg, err
+	}
+	switch cmd.eventType {
+	case event.Resize:
+		cmd.eventType = event.VerticalResize
+	case event.New:
+		cmd.eventType = event.Vnew
+	case event.Split:
+		cmd.eventType = event.Vsplit
+	default:
+		return command{}, nil, false, "", "", fmt.Errorf("invalid command for vertical: %s", cmd.name)
 	}
-	return command{}, nil, "", "", fmt.Errorf("unknown command: %s", string(cmdline))
+	return cmd, r, bang, "", arg, nil
 }
 
 func expand(name string) []string {
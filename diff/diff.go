@@ -0,0 +1,180 @@
+package diff
+
+import (
+	"io"
+	"sort"
+)
+
+// Hunk represents a pair of differing regions, [AFrom, ATo) of the first
+// sequence and [BFrom, BTo) of the second sequence. One of the regions can
+// be empty for the aligned differences.
+type Hunk struct {
+	AFrom, ATo int64
+	BFrom, BTo int64
+}
+
+const chunkSize = 64 * 1024
+
+// Positional compares the two sequences at the same offsets.
+func Positional(a, b io.ReaderAt, alen, blen int64) ([]Hunk, error) {
+	var hunks []Hunk
+	n := alen
+	if blen < n {
+		n = blen
+	}
+	abuf, bbuf := make([]byte, chunkSize), make([]byte, chunkSize)
+	start := int64(-1)
+	for offset := int64(0); offset < n; offset += chunkSize {
+		size := chunkSize
+		if n-offset < chunkSize {
+			size = int(n - offset)
+		}
+		if err := readFull(a, abuf[:size], offset); err != nil {
+			return nil, err
+		}
+		if err := readFull(b, bbuf[:size], offset); err != nil {
+			return nil, err
+		}
+		for i := 0; i < size; i++ {
+			if abuf[i] != bbuf[i] {
+				if start < 0 {
+					start = offset + int64(i)
+				}
+			} else if start >= 0 {
+				hunks = append(hunks, Hunk{start, offset + int64(i), start, offset + int64(i)})
+				start = -1
+			}
+		}
+	}
+	if start >= 0 {
+		hunks = append(hunks, Hunk{start, n, start, n})
+	}
+	if alen != blen {
+		if l := len(hunks); l > 0 && hunks[l-1].ATo == n {
+			hunks[l-1].ATo, hunks[l-1].BTo = alen, blen
+		} else {
+			hunks = append(hunks, Hunk{n, alen, n, blen})
+		}
+	}
+	return hunks, nil
+}
+
+func readFull(r io.ReaderAt, p []byte, offset int64) error {
+	n, err := r.ReadAt(p, offset)
+	if n == len(p) {
+		return nil
+	}
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Aligned compares the two sequences allowing insertions and deletions,
+// using the O(ND) algorithm by Myers. It gives up when the number of the
+// inserted and deleted bytes exceeds maxCost, returning false.
+func Aligned(a, b []byte, maxCost int) ([]Hunk, bool) {
+	// skip the common prefix and suffix, which is common for binary files
+	var prefix, suffix int
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-suffix-1] == b[len(b)-suffix-1] {
+		suffix++
+	}
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil, true
+	}
+	max := n + m
+	if max > maxCost {
+		max = maxCost
+	}
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace[d] holds v[-d:d+1] before the d-th step for the backtracking
+	var trace [][]int
+	found := false
+	for d := 0; d <= max && !found; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return nil, false
+	}
+	// backtrack the edit path to collect the differing regions
+	var hunks []Hunk
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v, k := trace[d], x-y
+		prevK := k - 1
+		if k == -d || k != d && v[k-1+d] < v[k+1+d] {
+			prevK = k + 1
+		}
+		prevX := v[prevK+d]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+		}
+		ax, ay := int64(prefix+x), int64(prefix+y)
+		if l := len(hunks); l > 0 && hunks[l-1].AFrom == ax && hunks[l-1].BFrom == ay {
+			hunks[l-1].AFrom, hunks[l-1].BFrom = int64(prefix+prevX), int64(prefix+prevY)
+		} else {
+			hunks = append(hunks, Hunk{int64(prefix + prevX), ax, int64(prefix + prevY), ay})
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(hunks)-1; i < j; i, j = i+1, j-1 {
+		hunks[i], hunks[j] = hunks[j], hunks[i]
+	}
+	return hunks, true
+}
+
+// Map maps the position of the first sequence to the corresponding position
+// of the second sequence. When reverse is true, maps in the other direction.
+func Map(hunks []Hunk, pos int64, reverse bool) int64 {
+	ranges := func(h Hunk) (int64, int64, int64, int64) {
+		if reverse {
+			return h.BFrom, h.BTo, h.AFrom, h.ATo
+		}
+		return h.AFrom, h.ATo, h.BFrom, h.BTo
+	}
+	i := sort.Search(len(hunks), func(i int) bool {
+		_, to, _, _ := ranges(hunks[i])
+		return pos < to
+	})
+	if i < len(hunks) {
+		if from, _, otherFrom, otherTo := ranges(hunks[i]); from <= pos {
+			if otherFrom == otherTo {
+				return otherFrom
+			}
+			if p := otherFrom + pos - from; p < otherTo {
+				return p
+			}
+			return otherTo - 1
+		}
+	}
+	if i == 0 {
+		return pos
+	}
+	_, to, _, otherTo := ranges(hunks[i-1])
+	return otherTo + pos - to
+}
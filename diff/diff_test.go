@@ -0,0 +1,102 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPositional(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected []Hunk
+	}{
+		{"", "", nil},
+		{"abcdef", "abcdef", nil},
+		{"abcdef", "abxdyy", []Hunk{{2, 3, 2, 3}, {4, 6, 4, 6}}},
+		{"abcdef", "abc", []Hunk{{3, 6, 3, 3}}},
+		{"abcdef", "abcxefgh", []Hunk{{3, 4, 3, 4}, {6, 6, 6, 8}}},
+		{"abcdef", "abcdex", []Hunk{{5, 6, 5, 6}}},
+		{"abcdef", "abcdexyz", []Hunk{{5, 6, 5, 8}}},
+	}
+	for _, tc := range testCases {
+		got, err := Positional(strings.NewReader(tc.a), strings.NewReader(tc.b),
+			int64(len(tc.a)), int64(len(tc.b)))
+		if err != nil {
+			t.Errorf("err should be nil but got %v", err)
+		}
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Positional(%q, %q) should be %v but got %v", tc.a, tc.b, tc.expected, got)
+		}
+	}
+
+	a, b := strings.Repeat("x", chunkSize+10), strings.Repeat("x", chunkSize-2)+"yyyy"
+	got, err := Positional(strings.NewReader(a), strings.NewReader(b), int64(len(a)), int64(len(b)))
+	if err != nil {
+		t.Errorf("err should be nil but got %v", err)
+	}
+	expected := []Hunk{{chunkSize - 2, chunkSize + 10, chunkSize - 2, chunkSize + 2}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Positional should be %v but got %v", expected, got)
+	}
+}
+
+func TestAligned(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected []Hunk
+	}{
+		{"", "", nil},
+		{"abcdef", "abcdef", nil},
+		{"abcdef", "abxdef", []Hunk{{2, 3, 2, 3}}},
+		{"abcdef", "abcxxdef", []Hunk{{3, 3, 3, 5}}},
+		{"abcdef", "abef", []Hunk{{2, 4, 2, 2}}},
+		{"abcdef", "xabcdefy", []Hunk{{0, 0, 0, 1}, {6, 6, 7, 8}}},
+		{"abcdef", "bcxdf", []Hunk{{0, 1, 0, 0}, {3, 3, 2, 3}, {4, 5, 4, 4}}},
+		{"abc", "xyz", []Hunk{{0, 3, 0, 3}}},
+	}
+	for _, tc := range testCases {
+		got, ok := Aligned([]byte(tc.a), []byte(tc.b), 100)
+		if !ok {
+			t.Errorf("Aligned(%q, %q) should succeed", tc.a, tc.b)
+		}
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Aligned(%q, %q) should be %v but got %v", tc.a, tc.b, tc.expected, got)
+		}
+	}
+
+	if _, ok := Aligned([]byte("abcdefgh"), []byte("hgfedcba"), 4); ok {
+		t.Errorf("Aligned should give up when the cost exceeds the limit")
+	}
+}
+
+func TestMap(t *testing.T) {
+	hunks := []Hunk{{2, 3, 2, 5}, {6, 8, 8, 8}, {10, 10, 10, 12}}
+	testCases := []struct {
+		pos      int64
+		reverse  bool
+		expected int64
+	}{
+		{0, false, 0},
+		{2, false, 2},
+		{3, false, 5},
+		{5, false, 7},
+		{6, false, 8},
+		{7, false, 8},
+		{8, false, 8},
+		{10, false, 12},
+		{20, false, 22},
+		{3, true, 2},
+		{4, true, 2},
+		{5, true, 3},
+		{8, true, 8},
+		{11, true, 10},
+		{12, true, 10},
+		{22, true, 20},
+	}
+	for _, tc := range testCases {
+		if got := Map(hunks, tc.pos, tc.reverse); got != tc.expected {
+			t.Errorf("Map(%d, %v) should be %d but got %d", tc.pos, tc.reverse, tc.expected, got)
+		}
+	}
+}
@@ -6,5 +6,6 @@ import "github.com/itchyny/bed/event"
 type Cmdline interface {
 	Init(chan<- event.Event, <-chan event.Event, chan<- struct{})
 	Run()
+	Parse(string) (event.Event, error)
 	Get() ([]rune, int, []string, int)
 }
@@ -25,6 +25,7 @@ type Editor struct {
 	eventCh       chan event.Event
 	redrawCh      chan struct{}
 	cmdlineCh     chan event.Event
+	commands      []string
 	mu            *sync.Mutex
 }
 
@@ -57,15 +58,39 @@ func (e *Editor) listen() {
 			e.redraw()
 		}
 	}()
+	for _, cmd := range e.commands {
+		ev, err := e.cmdline.Parse(cmd)
+		if err != nil {
+			ev = event.Event{Type: event.Error, Error: err}
+		} else if ev.Type == event.Nop {
+			continue
+		}
+		if e.handle(ev) {
+			return
+		}
+		// handle the events emitted by the command before the next command
+		for len(e.eventCh) > 0 {
+			if e.handle(<-e.eventCh) {
+				return
+			}
+		}
+	}
 	for ev := range e.eventCh {
-		if redraw, finish := e.emit(ev); redraw {
-			e.redrawCh <- struct{}{}
-		} else if finish {
+		if e.handle(ev) {
 			break
 		}
 	}
 }
 
+func (e *Editor) handle(ev event.Event) (finish bool) {
+	if redraw, finish := e.emit(ev); redraw {
+		e.redrawCh <- struct{}{}
+	} else if finish {
+		return true
+	}
+	return false
+}
+
 func (e *Editor) emit(ev event.Event) (redraw bool, finish bool) {
 	e.mu.Lock()
 	if ev.Type != event.Redraw {
@@ -76,6 +101,9 @@ func (e *Editor) emit(ev event.Event) (redraw bool, finish bool) {
 		if len(ev.Arg) > 0 {
 			e.err, e.errtyp = fmt.Errorf("too many arguments for %s", ev.CmdName), state.MessageError
 			redraw = true
+		} else if err := e.wm.CheckModified(); err != nil && !ev.Bang {
+			e.err, e.errtyp = err, state.MessageError
+			redraw = true
 		} else {
 			finish = true
 		}
@@ -93,6 +121,15 @@ func (e *Editor) emit(ev event.Event) (redraw bool, finish bool) {
 			return
 		}
 		redraw = true
+	case event.WriteCommand:
+		e.mu.Unlock()
+		if err := e.writeCommand(ev); err != nil {
+			e.mu.Lock()
+			e.err, e.errtyp = err, state.MessageError
+			e.mu.Unlock()
+		}
+		redraw = true
+		return
 	case event.Info:
 		e.err, e.errtyp = ev.Error, state.MessageInfo
 		redraw = true
@@ -167,11 +204,69 @@ func (e *Editor) Open(filename string) (err error) {
 	return e.wm.Open(filename)
 }
 
+// WindowLayout represents how the windows are laid out for the files.
+type WindowLayout int
+
+// Window layouts for opening multiple files.
+const (
+	LayoutBuffers WindowLayout = iota
+	LayoutHorizontal
+	LayoutVertical
+	LayoutTabs
+)
+
+// OpenFiles loads the files into the buffer list, and opens the windows.
+// The first file is shown unless the files are split into windows or tabs.
+func (e *Editor) OpenFiles(filenames []string, layout WindowLayout) (err error) {
+	for _, filename := range filenames {
+		if err = e.wm.Load(filename); err != nil {
+			return err
+		}
+	}
+	switch layout {
+	case LayoutHorizontal, LayoutVertical:
+		if err = e.wm.Open(filenames[len(filenames)-1]); err != nil {
+			return err
+		}
+		for i := len(filenames) - 2; i >= 0; i-- {
+			if err = e.wm.Split(filenames[i], layout == LayoutVertical); err != nil {
+				return err
+			}
+		}
+	case LayoutTabs:
+		if err = e.wm.Open(filenames[0]); err != nil {
+			return err
+		}
+		for _, filename := range filenames[1:] {
+			if err = e.wm.AddTabPage(filename); err != nil {
+				return err
+			}
+		}
+	default:
+		err = e.wm.Open(filenames[0])
+	}
+	return err
+}
+
+// OpenDiff opens the files side by side in diff mode.
+func (e *Editor) OpenDiff(filenames []string) (err error) {
+	if err = e.OpenFiles(filenames, LayoutVertical); err != nil {
+		return err
+	}
+	e.wm.Diff()
+	return nil
+}
+
 // OpenEmpty creates a new window.
 func (e *Editor) OpenEmpty() (err error) {
 	return e.wm.Open("")
 }
 
+// Execute the command after the editor starts.
+func (e *Editor) Execute(cmd string) {
+	e.commands = append(e.commands, cmd)
+}
+
 // Run the editor.
 func (e *Editor) Run() error {
 	if err := e.ui.Init(e.eventCh); err != nil {
@@ -195,10 +290,14 @@ func (e *Editor) redraw() (err error) {
 	if err != nil {
 		return err
 	}
+	if err := e.wm.CheckChanged(); err != nil {
+		e.err, e.errtyp = err, state.MessageError
+	}
 	if s.WindowStates[windowIndex] == nil {
 		return errors.New("index out of windows")
 	}
 	s.WindowStates[windowIndex].Mode = e.mode
+	s.TabPages, s.TabIndex = e.wm.TabPages()
 	s.Mode, s.PrevMode, s.Error, s.ErrorType = e.mode, e.prevMode, e.err, e.errtyp
 	if s.Mode != mode.Visual && s.PrevMode != mode.Visual {
 		for _, ws := range s.WindowStates {
@@ -226,9 +325,9 @@ func (e *Editor) suspend() error {
 
 // Close terminates the editor.
 func (e *Editor) Close() error {
+	e.wm.Close()
 	close(e.eventCh)
 	close(e.redrawCh)
 	close(e.cmdlineCh)
-	e.wm.Close()
 	return e.ui.Close()
 }
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,6 +15,7 @@ import (
 	"github.com/itchyny/bed/cmdline"
 	"github.com/itchyny/bed/event"
 	"github.com/itchyny/bed/key"
+	"github.com/itchyny/bed/layout"
 	"github.com/itchyny/bed/mode"
 	"github.com/itchyny/bed/state"
 	"github.com/itchyny/bed/window"
@@ -240,3 +243,189 @@ func TestEditorCmdlineQuit(t *testing.T) {
 		t.Errorf("err should be nil but got: %v", err)
 	}
 }
+
+func TestEditorQuitModified(t *testing.T) {
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := editor.OpenEmpty(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	var err error
+	go func() {
+		ui.Emit(event.Event{Type: event.Increment})
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.QuitAll})
+		time.Sleep(100 * time.Millisecond)
+		editor.mu.Lock()
+		err = editor.err
+		editor.mu.Unlock()
+		ui.Emit(event.Event{Type: event.Quit, Bang: true})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	expected := "no write since last change (add ! to override)"
+	if err == nil || err.Error() != expected {
+		t.Errorf("err should be %q but got: %v", expected, err)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+}
+
+func TestEditorWriteCommand(t *testing.T) {
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "bed-test-editor-write-command")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stdin, err := os.Create(filepath.Join(dir, "stdin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	if _, err := stdin.WriteString("\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stdin.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+	defer func(stdin, stdout *os.File) { os.Stdin, os.Stdout = stdin, stdout }(os.Stdin, os.Stdout)
+	os.Stdin, os.Stdout = stdin, stdout
+	if err := editor.OpenEmpty(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	output := filepath.Join(dir, "output")
+	go func() {
+		for _, t := range []event.Type{event.Increment, event.Increment, event.Increment} {
+			ui.Emit(event.Event{Type: t})
+		}
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.WriteCommand, Arg: "cat > " + output})
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.Quit, Bang: true})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := editor.err; err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	bs, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if string(bs) != "\x03" {
+		t.Errorf("file contents should be %q but got %q", "\x03", string(bs))
+	}
+}
+
+func TestEditorOpenFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bed-test-editor-open-files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var filenames, names []string
+	for _, name := range []string{"a.bin", "b.bin", "c.bin"} {
+		filenames = append(filenames, filepath.Join(dir, name))
+		names = append(names, name)
+	}
+	for _, testCase := range []struct {
+		layout   WindowLayout
+		windows  int
+		typ      layout.Layout
+		tabPages []string
+	}{
+		{LayoutBuffers, 1, layout.Window{}, []string{"a.bin"}},
+		{LayoutHorizontal, 3, layout.Horizontal{}, []string{"3 a.bin"}},
+		{LayoutVertical, 3, layout.Vertical{}, []string{"3 a.bin"}},
+		{LayoutTabs, 1, layout.Window{}, names},
+	} {
+		editor := NewEditor(newTestUI(), window.NewManager(), cmdline.NewCmdline())
+		if err := editor.Init(); err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if err := editor.OpenFiles(filenames, testCase.layout); err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		windowStates, l, windowIndex, err := editor.wm.State()
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if len(windowStates) != testCase.windows {
+			t.Errorf("windows should be %d but got %d", testCase.windows, len(windowStates))
+		}
+		if reflect.TypeOf(l) != reflect.TypeOf(testCase.typ) {
+			t.Errorf("layout should be %T but got %T", testCase.typ, l)
+		}
+		if name := windowStates[windowIndex].Name; name != "a.bin" {
+			t.Errorf("name should be %q but got %q", "a.bin", name)
+		}
+		if tabPages, tabIndex := editor.wm.TabPages(); !reflect.DeepEqual(tabPages, testCase.tabPages) || tabIndex != 0 {
+			t.Errorf("tab pages should be %v and %d but got %v and %d", testCase.tabPages, 0, tabPages, tabIndex)
+		}
+		editor.wm.Emit(event.Event{Type: event.Buffers})
+		if e := <-editor.eventCh; e.Error == nil || !strings.HasPrefix(e.Error.Error(), `1 %a "a.bin", 2 `) ||
+			!strings.Contains(e.Error.Error(), `"c.bin"`) {
+			t.Errorf("buffer list should contain all the files but got %v", e.Error)
+		}
+		if err := editor.Close(); err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+	}
+}
+
+func TestEditorExecute(t *testing.T) {
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	f, err := ioutil.TempFile("", "bed-test-editor-execute")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if _, err := f.WriteString("Hello, world!"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if err := editor.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	editor.Execute("5,$w " + f.Name() + ".out")
+	editor.Execute(":quit")
+	defer os.Remove(f.Name() + ".out")
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	bs, err := ioutil.ReadFile(f.Name() + ".out")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if string(bs) != ", world!" {
+		t.Errorf("file contents should be %q but got %q", ", world!", string(bs))
+	}
+}
@@ -41,7 +41,17 @@ func defaultKeyManagers() map[mode.Mode]*key.Manager {
 	km.Register(event.PageTop, "g", "g")
 	km.Register(event.PageEnd, "G")
 	km.Register(event.JumpTo, "\x1d")
+	km.Register(event.JumpToPointer, "g", "\x1d")
+	km.Register(event.TabNext, "g", "t")
+	km.Register(event.TabPrev, "g", "T")
+	km.Register(event.NextDiff, "]", "c")
+	km.Register(event.PrevDiff, "[", "c")
 	km.Register(event.JumpBack, "c-t")
+	for c := 'a'; c <= 'z'; c++ {
+		km.Register(event.SetMark, "m", key.Key(c))
+		km.Register(event.GotoMark, "'", key.Key(c))
+		km.Register(event.GotoMark, "`", key.Key(c))
+	}
 	km.Register(event.DeleteByte, "x")
 	km.Register(event.DeletePrevByte, "X")
 	km.Register(event.Increment, "c-a")
@@ -71,6 +81,16 @@ func defaultKeyManagers() map[mode.Mode]*key.Manager {
 
 	km.Register(event.New, "c-w", "n")
 	km.Register(event.New, "c-w", "c-n")
+	km.Register(event.Split, "c-w", "s")
+	km.Register(event.Split, "c-w", "S")
+	km.Register(event.Split, "c-w", "c-s")
+	km.Register(event.Vsplit, "c-w", "v")
+	km.Register(event.Vsplit, "c-w", "c-v")
+	km.Register(event.Close, "c-w", "c")
+	km.Register(event.Only, "c-w", "o")
+	km.Register(event.Only, "c-w", "c-o")
+	km.Register(event.Quit, "c-w", "q")
+	km.Register(event.Quit, "c-w", "c-q")
 	km.Register(event.FocusWindowDown, "c-w", "down")
 	km.Register(event.FocusWindowDown, "c-w", "c-j")
 	km.Register(event.FocusWindowDown, "c-w", "j")
@@ -94,6 +114,19 @@ func defaultKeyManagers() map[mode.Mode]*key.Manager {
 	km.Register(event.MoveWindowBottom, "c-w", "J")
 	km.Register(event.MoveWindowLeft, "c-w", "H")
 	km.Register(event.MoveWindowRight, "c-w", "L")
+	km.Register(event.ExchangeWindow, "c-w", "x")
+	km.Register(event.ExchangeWindow, "c-w", "c-x")
+	km.Register(event.RotateWindowsDownwards, "c-w", "r")
+	km.Register(event.RotateWindowsDownwards, "c-w", "c-r")
+	km.Register(event.RotateWindowsUpwards, "c-w", "R")
+	km.Register(event.IncreaseWindowHeight, "c-w", "+")
+	km.Register(event.DecreaseWindowHeight, "c-w", "-")
+	km.Register(event.IncreaseWindowWidth, "c-w", ">")
+	km.Register(event.DecreaseWindowWidth, "c-w", "<")
+	km.Register(event.EqualizeWindows, "c-w", "=")
+	km.Register(event.SetWindowHeight, "c-w", "_")
+	km.Register(event.SetWindowHeight, "c-w", "c-_")
+	km.Register(event.SetWindowWidth, "c-w", "|")
 	kms[mode.Normal] = km
 
 	km = key.NewManager(false)
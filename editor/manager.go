@@ -1,6 +1,8 @@
 package editor
 
 import (
+	"io"
+
 	"github.com/itchyny/bed/event"
 	"github.com/itchyny/bed/layout"
 	"github.com/itchyny/bed/state"
@@ -10,9 +12,17 @@ import (
 type Manager interface {
 	Init(chan<- event.Event, chan<- struct{})
 	Open(string) error
+	Load(string) error
+	Split(string, bool) error
+	AddTabPage(string) error
+	Diff()
+	CheckModified() error
+	CheckChanged() error
+	WriteTo(*event.Range, io.Writer) (int64, error)
 	SetSize(int, int)
 	Resize(int, int)
 	Emit(event.Event)
 	State() (map[int]*state.WindowState, layout.Layout, int, error)
+	TabPages() ([]string, int)
 	Close()
 }
@@ -0,0 +1,40 @@
+package editor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/shell"
+)
+
+// writeCommand pipes the buffer or the range to the shell command. The user
+// interface is closed while the command runs, and the output is shown on the
+// terminal until the enter key is pressed.
+func (e *Editor) writeCommand(ev event.Event) error {
+	if ev.Arg == "" {
+		return errors.New("no command to write to")
+	}
+	if err := e.ui.Close(); err != nil {
+		return err
+	}
+	r, w := io.Pipe()
+	go func() {
+		_, err := e.wm.WriteTo(ev.Range, w)
+		w.CloseWithError(err)
+	}()
+	cmd := shell.Command(ev.Arg)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = r, os.Stdout, os.Stderr
+	err := cmd.Run()
+	r.Close()
+	fmt.Fprint(os.Stdout, "\nPress ENTER to continue")
+	_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+	if err := e.ui.Init(e.eventCh); err != nil {
+		return err
+	}
+	go e.ui.Run(defaultKeyManagers())
+	return err
+}
@@ -9,6 +9,7 @@ type Event struct {
 	Count   int64
 	Rune    rune
 	CmdName string
+	Bang    bool
 	Arg     string
 	Error   error
 	Mode    mode.Mode
@@ -40,7 +41,10 @@ const (
 	PageTop
 	PageEnd
 	JumpTo
+	JumpToPointer
 	JumpBack
+	GotoMark
+	SetMark
 
 	DeleteByte
 	DeletePrevByte
@@ -84,8 +88,13 @@ const (
 	PreviousSearch
 
 	Edit
+	View
 	New
 	Vnew
+	Split
+	Vsplit
+	Close
+	Only
 	Wincmd
 	FocusWindowUp
 	FocusWindowDown
@@ -98,11 +107,46 @@ const (
 	MoveWindowBottom
 	MoveWindowLeft
 	MoveWindowRight
+	ExchangeWindow
+	RotateWindowsDownwards
+	RotateWindowsUpwards
+	IncreaseWindowHeight
+	DecreaseWindowHeight
+	IncreaseWindowWidth
+	DecreaseWindowWidth
+	EqualizeWindows
+	SetWindowHeight
+	SetWindowWidth
+	Resize
+	VerticalResize
+	Vertical
+	Marks
+	Set
+	Echo
+	Execute
+	Buffers
+	Buffer
+	BufferNext
+	BufferPrev
+	BufferDelete
+	TabNew
+	TabClose
+	TabNext
+	TabPrev
+	DiffThis
+	DiffOff
+	DiffGet
+	DiffPut
+	NextDiff
+	PrevDiff
 	Suspend
 	Quit
 	QuitAll
 	Write
 	WriteQuit
+	WriteCommand
+	Read
+	Filter
 	Info
 	Error
 )
@@ -1,18 +1,25 @@
 package event
 
-import "unicode"
+import (
+	"unicode"
+
+	"github.com/itchyny/bed/expr"
+)
 
 // ParseRange parses a Range.
-func ParseRange(xs []rune, i int) (*Range, int) {
-	from, i := ParsePos(xs, i)
-	if from == nil {
-		return nil, i
+func ParseRange(xs []rune, i int) (*Range, int, error) {
+	from, i, err := ParsePos(xs, i)
+	if err != nil || from == nil {
+		return nil, i, err
 	}
 	if i >= len(xs) || xs[i] != ',' {
-		return &Range{From: from}, i
+		return &Range{From: from}, i, nil
+	}
+	to, i, err := ParsePos(xs, i+1)
+	if err != nil {
+		return nil, i, err
 	}
-	to, i := ParsePos(xs, i+1)
-	return &Range{From: from, To: to}, i
+	return &Range{From: from, To: to}, i, nil
 }
 
 var states = map[int]map[rune]struct {
@@ -31,36 +38,42 @@ var states = map[int]map[rune]struct {
 }
 
 // ParsePos parses a Position.
-//    +---- num.. ----+
-//    +-- [-+]num.. --+   +---------------+
-//    +------ $ ------+   |               |
-// ---+------ . ------+---+-- [-+]num.. --+---
-//    +-- ' -+- < -+--+
+//    +---- expr ----+
+//    +-- [-+]term --+   +--------------+
+//    +------ $ -----+   |              |
+// ---+------ . -----+---+-- [-+]term --+---
+//    +-- ' -+- < -+-+
 //           +- > -+
-func ParsePos(xs []rune, i int) (Position, int) {
+//           +- a -+
+//           |  :  |
+//           +- z -+
+//    +-- / pat / ---+
+//    +-- ? pat ? ---+
+func ParsePos(xs []rune, i int) (Position, int, error) {
 	var state int
 	var position Position
 	for ; i < len(xs); i++ {
 		if state <= 1 && unicode.IsSpace(xs[i]) {
 			continue
 		}
-		if state == 0 && '0' <= xs[i] && xs[i] <= '9' {
-			var offset int64
-			offset, i = parseNum(xs, i)
-			if position == nil {
-				position = Absolute{offset}
+		if state == 0 && ('0' <= xs[i] && xs[i] <= '9' || xs[i] == '(') {
+			offset, j, err := expr.ParseInt(xs, i)
+			if err != nil {
+				return nil, j, err
 			}
+			position, i = Absolute{offset}, j-1
 			state = 1
 			continue
 		}
 		if state <= 1 && (xs[i] == '+' || xs[i] == '-') {
-			var offset int64
-			sign := int64(1)
+			offset, j, err := expr.ParseTerm(xs, i+1)
+			if err != nil {
+				return nil, j, err
+			}
 			if xs[i] == '-' {
-				sign = -1
+				offset = -offset
 			}
-			offset, i = parseNum(xs, i+1)
-			offset *= sign
+			i = j - 1
 			if position == nil {
 				position = Relative{offset}
 			} else {
@@ -69,44 +82,80 @@ func ParsePos(xs []rune, i int) (Position, int) {
 			state = 1
 			continue
 		}
+		if state == 0 && (xs[i] == '/' || xs[i] == '?') {
+			forward := xs[i] == '/'
+			var pattern string
+			pattern, i = parsePattern(xs, i)
+			position = Pattern{Pattern: pattern, Forward: forward}
+			state = 1
+			continue
+		}
+		if state == 2 && 'a' <= xs[i] && xs[i] <= 'z' {
+			state = 1
+			position = Mark{Name: xs[i]}
+			continue
+		}
 		if s, ok := states[state]; ok {
 			if next, ok := s[xs[i]]; ok {
 				state = next.state
 				position = next.position
 			} else {
-				return position, i
+				return position, i, nil
 			}
 		} else {
-			return position, i
+			return position, i, nil
 		}
 	}
-	return position, i
+	return position, i, nil
 }
 
-func parseNum(xs []rune, i int) (int64, int) {
-	var offset int64
-	var hex int
-	for ; i < len(xs); i++ {
-		c := xs[i]
-		if hex == 0 && c == '0' {
-			hex = 1
-		} else if hex == 1 && c == 'x' {
-			hex = 2
-		} else if '0' <= c && c <= '9' || hex == 2 && 'a' <= c && c <= 'f' {
-			if hex == 2 {
-				offset *= 0x10
-			} else {
-				hex = 3
-				offset *= 10
-			}
-			if '0' <= c && c <= '9' {
-				offset += int64(c - '0')
+func parsePattern(xs []rune, i int) (string, int) {
+	delim := xs[i]
+	var bs []byte
+	for i++; i < len(xs) && xs[i] != delim; i++ {
+		if xs[i] != '\\' || i+1 == len(xs) {
+			bs = append(bs, string(xs[i])...)
+			continue
+		}
+		i++
+		switch xs[i] {
+		case delim, '\\':
+			bs = append(bs, string(xs[i])...)
+		case 'n':
+			bs = append(bs, '\n')
+		case 'r':
+			bs = append(bs, '\r')
+		case 't':
+			bs = append(bs, '\t')
+		case '0':
+			bs = append(bs, '\x00')
+		case 'x':
+			if i+2 < len(xs) && digitValue(xs[i+1]) < 16 && digitValue(xs[i+2]) < 16 {
+				bs = append(bs, byte(digitValue(xs[i+1])<<4|digitValue(xs[i+2])))
+				i += 2
 			} else {
-				offset += int64(c - 'a' + 0x0a)
+				bs = append(bs, '\\', 'x')
 			}
-		} else {
-			return offset, i - 1
+		default:
+			bs = append(bs, '\\')
+			bs = append(bs, string(xs[i])...)
 		}
 	}
-	return offset, i - 1
+	if i == len(xs) {
+		i--
+	}
+	return string(bs), i
+}
+
+func digitValue(c rune) int64 {
+	switch {
+	case '0' <= c && c <= '9':
+		return int64(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int64(c - 'a' + 0x0a)
+	case 'A' <= c && c <= 'F':
+		return int64(c - 'A' + 0x0a)
+	default:
+		return 16
+	}
 }
@@ -21,9 +21,18 @@ func TestParseRange(t *testing.T) {
 		{"'>", &Range{VisualEnd{}, nil}, 2},
 		{" '<  ,  '>  write", &Range{VisualStart{}, VisualEnd{}}, 12},
 		{" '<+0x10 ,  '>-10 ", &Range{VisualStart{0x10}, VisualEnd{-10}}, 18},
+		{"'a,'b", &Range{Mark{'a', 0}, Mark{'b', 0}}, 5},
+		{" 'x+0x10 , 'y-1 w", &Range{Mark{'x', 0x10}, Mark{'y', -1}}, 16},
+		{"0x200+3*0x40,(0x200+4*0x40)-1", &Range{Absolute{0x2c0}, Absolute{0x2ff}}, 29},
+		{" . + 2 * 8 , $ - 0x10 << 1 ", &Range{Relative{16}, End{-0x10}}, 22},
+		{"/MZ/,/PE/-1w header.bin", &Range{Pattern{"MZ", true, 0}, Pattern{"PE", true, -1}}, 11},
+		{"?a/b?,$", &Range{Pattern{"a/b", false, 0}, End{}}, 7},
 	}
 	for _, testCase := range testCases {
-		got, gotIndex := ParseRange([]rune(testCase.target), 0)
+		got, gotIndex, err := ParseRange([]rune(testCase.target), 0)
+		if err != nil {
+			t.Errorf("ParseRange(%q) should not return an error but got %v", testCase.target, err)
+		}
 		if !reflect.DeepEqual(got, testCase.expected) {
 			t.Errorf("ParseRange(%q) should return %#v but got %#v", testCase.target, testCase.expected, got)
 		}
@@ -54,9 +63,33 @@ func TestParsePos(t *testing.T) {
 		{"'>", VisualEnd{}, 2},
 		{" '<  ,  '> ", VisualStart{}, 5},
 		{" '<+0x10 ,  '>-10 ", VisualStart{0x10}, 9},
+		{"0xFFFF", Absolute{65535}, 6},
+		{"0XaBcD", Absolute{0xabcd}, 6},
+		{"0b1011+0o17", Absolute{0xb + 0xf}, 11},
+		{"0x200+3*0x40", Absolute{0x2c0}, 12},
+		{"(1+2)*(3+4)", Absolute{21}, 11},
+		{"1+2*3-4/2", Absolute{5}, 9},
+		{"100%7", Absolute{2}, 5},
+		{"1<<12|3", Absolute{4096}, 5},
+		{"0x10000>>4 ", Absolute{0x1000}, 11},
+		{"-(1+2)", Relative{-3}, 6},
+		{"10-2*3", Absolute{4}, 6},
+		{".-2*3+1", Relative{-5}, 7},
+		{"$-(0x10*2)", End{-0x20}, 10},
+		{"'<+2*8", VisualStart{16}, 6},
+		{"'a", Mark{'a', 0}, 2},
+		{" 'z-0x20 ", Mark{'z', -0x20}, 9},
+		{"'A", nil, 1},
+		{`/PK\x03\x04/+4`, Pattern{"PK\x03\x04", true, 4}, 14},
+		{`/a\/b\\c\td\xe/`, Pattern{"a/b\\c\td\\xe", true, 0}, 15},
+		{`?\?\xFF\0?-0x10`, Pattern{"?\xff\x00", false, -0x10}, 15},
+		{"/unterminated", Pattern{"unterminated", true, 0}, 13},
 	}
 	for _, testCase := range testCases {
-		got, gotIndex := ParsePos([]rune(testCase.target), 0)
+		got, gotIndex, err := ParsePos([]rune(testCase.target), 0)
+		if err != nil {
+			t.Errorf("ParsePos(%q) should not return an error but got %v", testCase.target, err)
+		}
 		if !reflect.DeepEqual(got, testCase.expected) {
 			t.Errorf("ParsePos(%q) should return %#v but got %#v", testCase.target, testCase.expected, got)
 		}
@@ -65,3 +98,29 @@ func TestParsePos(t *testing.T) {
 		}
 	}
 }
+
+func TestParsePosError(t *testing.T) {
+	testCases := []struct {
+		target   string
+		expected string
+	}{
+		{"10/0", "division by zero"},
+		{"8%(1-1)", "division by zero"},
+		{"1<<64", "invalid shift count: 64"},
+		{"1>>-1", "invalid shift count: -1"},
+		{"(1+2", "missing closing parenthesis"},
+		{"0x", "invalid number: 0x"},
+		{"0b2", "invalid number: 0b"},
+		{".+0o", "invalid number: 0o"},
+		{"1+", "unexpected end of expression"},
+		{"2*x", "unexpected character: x"},
+	}
+	for _, testCase := range testCases {
+		_, _, err := ParsePos([]rune(testCase.target), 0)
+		if err == nil {
+			t.Errorf("ParsePos(%q) should return an error but got nil", testCase.target)
+		} else if err.Error() != testCase.expected {
+			t.Errorf("ParsePos(%q) should return error %q but got %q", testCase.target, testCase.expected, err.Error())
+		}
+	}
+}
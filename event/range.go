@@ -66,3 +66,28 @@ func (p VisualEnd) isPosition() {}
 func (p VisualEnd) addOffset(offset int64) Position {
 	return VisualEnd{p.Offset + offset}
 }
+
+// Mark is the position of the named mark.
+type Mark struct {
+	Name   rune
+	Offset int64
+}
+
+func (p Mark) isPosition() {}
+
+func (p Mark) addOffset(offset int64) Position {
+	return Mark{p.Name, p.Offset + offset}
+}
+
+// Pattern is the position of the pattern searched from the cursor.
+type Pattern struct {
+	Pattern string
+	Forward bool
+	Offset  int64
+}
+
+func (p Pattern) isPosition() {}
+
+func (p Pattern) addOffset(offset int64) Position {
+	return Pattern{p.Pattern, p.Forward, p.Offset + offset}
+}
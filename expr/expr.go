@@ -0,0 +1,513 @@
+package expr
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Value is the result of evaluation, either an int64 or a string.
+type Value interface{}
+
+// Env provides the buffer access for evaluation.
+type Env interface {
+	Cursor() int64
+	Length() int64
+	ReadAt([]byte, int64) (int, error)
+}
+
+// Eval evaluates the expression.
+//
+//	expr   := concat
+//	concat := shift (('.' | '..') shift)*
+//	shift  := sum (('<<' | '>>') sum)*
+//	sum    := term (('+' | '-') term)*
+//	term   := unary (('*' | '/' | '%') unary)*
+//	unary  := ('-' | '+' | '~')* primary
+//	primary := num | string | '.' | '$' | name '(' [expr (',' expr)*] ')' | '(' expr ')'
+func Eval(src string, env Env) (Value, error) {
+	p := &parser{xs: []rune(src), env: env}
+	v, err := p.concat()
+	if err != nil {
+		return nil, err
+	}
+	if i, c := p.peek(); c != '\x00' {
+		return nil, fmt.Errorf("unexpected character: %s", string(p.xs[i:]))
+	}
+	return v, nil
+}
+
+// Format returns the string representation of the value.
+// Integers are shown in hexadecimal, decimal and binary.
+func Format(v Value) string {
+	switch v := v.(type) {
+	case int64:
+		return fmt.Sprintf("%#x %d %#b", v, v, v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// String converts the value to string as the concatenation does.
+func String(v Value) string {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// ParseInt parses an integer expression at xs[i:] and returns the value and
+// the index after the expression. Only numbers, parentheses and operators are
+// allowed so that it can be used for the positions of ranges.
+func ParseInt(xs []rune, i int) (int64, int, error) {
+	p := &parser{xs: xs, pos: i}
+	v, err := p.shift()
+	if err != nil {
+		return 0, p.pos, err
+	}
+	return v.(int64), p.pos, nil
+}
+
+// ParseTerm parses a term of an integer expression like ParseInt.
+// The term binds tighter than addition so that the following signs
+// can be handled by the caller.
+func ParseTerm(xs []rune, i int) (int64, int, error) {
+	p := &parser{xs: xs, pos: i}
+	v, err := p.term()
+	if err != nil {
+		return 0, p.pos, err
+	}
+	return v.(int64), p.pos, nil
+}
+
+// parser evaluates the expression. The env is nil on parsing integer
+// expressions by ParseInt and ParseTerm.
+type parser struct {
+	xs  []rune
+	pos int
+	env Env
+}
+
+func (p *parser) peek() (int, rune) {
+	i := p.pos
+	for i < len(p.xs) && unicode.IsSpace(p.xs[i]) {
+		i++
+	}
+	if i < len(p.xs) {
+		return i, p.xs[i]
+	}
+	return i, '\x00'
+}
+
+func (p *parser) concat() (Value, error) {
+	v, err := p.shift()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		i, c := p.peek()
+		if c != '.' {
+			return v, nil
+		}
+		p.pos = i + 1
+		if p.pos < len(p.xs) && p.xs[p.pos] == '.' {
+			p.pos++
+		}
+		w, err := p.shift()
+		if err != nil {
+			return nil, err
+		}
+		v = String(v) + String(w)
+	}
+}
+
+func (p *parser) shift() (Value, error) {
+	v, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		i, c := p.peek()
+		if c != '<' && c != '>' || i+1 >= len(p.xs) || p.xs[i+1] != c {
+			return v, nil
+		}
+		p.pos = i + 2
+		w, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		x, y, err := numbers(v, w)
+		if err != nil {
+			return nil, err
+		}
+		if y < 0 || y >= 64 {
+			return nil, fmt.Errorf("invalid shift count: %d", y)
+		}
+		if c == '<' {
+			v = x << uint(y)
+		} else {
+			v = x >> uint(y)
+		}
+	}
+}
+
+func (p *parser) sum() (Value, error) {
+	v, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		i, c := p.peek()
+		if c != '+' && c != '-' {
+			return v, nil
+		}
+		p.pos = i + 1
+		w, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		x, y, err := numbers(v, w)
+		if err != nil {
+			return nil, err
+		}
+		if c == '+' {
+			v = x + y
+		} else {
+			v = x - y
+		}
+	}
+}
+
+func (p *parser) term() (Value, error) {
+	v, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		i, c := p.peek()
+		if c != '*' && c != '/' && c != '%' {
+			return v, nil
+		}
+		p.pos = i + 1
+		w, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		x, y, err := numbers(v, w)
+		if err != nil {
+			return nil, err
+		}
+		if c != '*' && y == 0 {
+			return nil, errors.New("division by zero")
+		}
+		switch c {
+		case '*':
+			v = x * y
+		case '/':
+			v = x / y
+		case '%':
+			v = x % y
+		}
+	}
+}
+
+func (p *parser) unary() (Value, error) {
+	i, c := p.peek()
+	if c != '-' && c != '+' && c != '~' {
+		return p.primary()
+	}
+	p.pos = i + 1
+	v, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	x, ok := v.(int64)
+	if !ok {
+		return nil, fmt.Errorf("expected a number but got %q", v)
+	}
+	switch c {
+	case '-':
+		return -x, nil
+	case '~':
+		return ^x, nil
+	}
+	return x, nil
+}
+
+func (p *parser) primary() (Value, error) {
+	i, c := p.peek()
+	p.pos = i
+	switch {
+	case c == '\x00':
+		return nil, errors.New("unexpected end of expression")
+	case c == '(':
+		p.pos++
+		var v Value
+		var err error
+		if p.env == nil {
+			v, err = p.shift()
+		} else {
+			v, err = p.concat()
+		}
+		if err != nil {
+			return nil, err
+		}
+		i, c := p.peek()
+		if c != ')' {
+			return nil, errors.New("missing closing parenthesis")
+		}
+		p.pos = i + 1
+		return v, nil
+	case '0' <= c && c <= '9':
+		return p.num()
+	case p.env == nil:
+		return nil, fmt.Errorf("unexpected character: %s", string(p.xs[i:]))
+	case c == '"' || c == '\'':
+		return p.str()
+	case c == '.':
+		p.pos++
+		return p.env.Cursor(), nil
+	case c == '$':
+		p.pos++
+		if l := p.env.Length(); l > 0 {
+			return l - 1, nil
+		}
+		return int64(0), nil
+	case isIdent(c):
+		return p.call()
+	default:
+		return nil, fmt.Errorf("unexpected character: %s", string(p.xs[i:]))
+	}
+}
+
+func (p *parser) num() (Value, error) {
+	base, prefix := 10, p.pos
+	if p.xs[p.pos] == '0' && p.pos+1 < len(p.xs) {
+		switch unicode.ToLower(p.xs[p.pos+1]) {
+		case 'x':
+			base = 16
+		case 'o':
+			base = 8
+		case 'b':
+			base = 2
+		}
+		if base != 10 {
+			p.pos += 2
+		}
+	}
+	start := p.pos
+	for p.pos < len(p.xs) && digitValue(p.xs[p.pos]) < base {
+		p.pos++
+	}
+	v, err := strconv.ParseInt(string(p.xs[start:p.pos]), base, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number: %s", string(p.xs[prefix:p.pos]))
+	}
+	return v, nil
+}
+
+func (p *parser) str() (Value, error) {
+	quote := p.xs[p.pos]
+	var sb strings.Builder
+	for p.pos++; p.pos < len(p.xs); p.pos++ {
+		c := p.xs[p.pos]
+		if c == quote {
+			p.pos++
+			return sb.String(), nil
+		}
+		if c == '\\' && quote == '"' && p.pos+1 < len(p.xs) {
+			p.pos++
+			switch p.xs[p.pos] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteRune(p.xs[p.pos])
+			}
+			continue
+		}
+		sb.WriteRune(c)
+	}
+	return nil, errors.New("missing closing quote")
+}
+
+func (p *parser) call() (Value, error) {
+	start := p.pos
+	for p.pos < len(p.xs) && (isIdent(p.xs[p.pos]) || '0' <= p.xs[p.pos] && p.xs[p.pos] <= '9') {
+		p.pos++
+	}
+	name := string(p.xs[start:p.pos])
+	i, c := p.peek()
+	if c != '(' {
+		return nil, fmt.Errorf("unknown variable: %s", name)
+	}
+	p.pos = i + 1
+	var args []Value
+	if i, c := p.peek(); c == ')' {
+		p.pos = i + 1
+	} else {
+		for {
+			v, err := p.concat()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, v)
+			i, c := p.peek()
+			p.pos = i + 1
+			if c == ')' {
+				break
+			} else if c != ',' {
+				return nil, fmt.Errorf("missing closing parenthesis for %s", name)
+			}
+		}
+	}
+	return p.apply(name, args)
+}
+
+func (p *parser) apply(name string, args []Value) (Value, error) {
+	switch name {
+	case "len":
+		if len(args) > 0 {
+			return nil, fmt.Errorf("too many arguments for %s", name)
+		}
+		return p.env.Length(), nil
+	case "cursor":
+		if len(args) > 0 {
+			return nil, fmt.Errorf("too many arguments for %s", name)
+		}
+		return p.env.Cursor(), nil
+	}
+	if size, _, _ := readFunc(name); size == 0 {
+		return nil, fmt.Errorf("unknown function: %s", name)
+	}
+	if len(args) > 1 {
+		return nil, fmt.Errorf("too many arguments for %s", name)
+	}
+	offset := p.env.Cursor()
+	if len(args) == 1 {
+		var ok bool
+		if offset, ok = args[0].(int64); !ok {
+			return nil, fmt.Errorf("expected a number but got %q", args[0])
+		}
+	}
+	v, err := ReadInt(p.env, name, offset)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ReadInt reads the integer at the offset in the format like u8, i16le or
+// u32be, which is the name of the reading function.
+func ReadInt(r io.ReaderAt, format string, offset int64) (int64, error) {
+	size, signed, order := readFunc(format)
+	if size == 0 {
+		return 0, fmt.Errorf("unknown format: %s", format)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("%s: negative offset: %d", format, offset)
+	}
+	bs := make([]byte, 8)
+	if n, _ := r.ReadAt(bs[:size], offset); n < size {
+		return 0, fmt.Errorf("%s: cannot read %d bytes at 0x%x", format, size, offset)
+	}
+	var v uint64
+	switch size {
+	case 1:
+		v = uint64(bs[0])
+		if signed {
+			return int64(int8(v)), nil
+		}
+	case 2:
+		v = uint64(order.Uint16(bs))
+		if signed {
+			return int64(int16(v)), nil
+		}
+	case 4:
+		v = uint64(order.Uint32(bs))
+		if signed {
+			return int64(int32(v)), nil
+		}
+	default:
+		v = order.Uint64(bs)
+	}
+	return int64(v), nil
+}
+
+// readFunc returns the byte size, signedness and byte order of the reading
+// function names like u8, i16le or u32be.
+func readFunc(name string) (int, bool, binary.ByteOrder) {
+	var signed bool
+	switch {
+	case strings.HasPrefix(name, "u"):
+	case strings.HasPrefix(name, "i"):
+		signed = true
+	default:
+		return 0, false, nil
+	}
+	name = name[1:]
+	if name == "8" {
+		return 1, signed, binary.LittleEndian
+	}
+	var order binary.ByteOrder
+	switch {
+	case strings.HasSuffix(name, "le"):
+		order = binary.LittleEndian
+	case strings.HasSuffix(name, "be"):
+		order = binary.BigEndian
+	default:
+		return 0, false, nil
+	}
+	switch name[:len(name)-2] {
+	case "16":
+		return 2, signed, order
+	case "32":
+		return 4, signed, order
+	case "64":
+		return 8, signed, order
+	default:
+		return 0, false, nil
+	}
+}
+
+func digitValue(c rune) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c - 'a' + 0x0a)
+	case 'A' <= c && c <= 'F':
+		return int(c - 'A' + 0x0a)
+	default:
+		return 16
+	}
+}
+
+func isIdent(c rune) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_'
+}
+
+func numbers(v, w Value) (int64, int64, error) {
+	x, ok := v.(int64)
+	if !ok {
+		return 0, 0, fmt.Errorf("expected a number but got %q", v)
+	}
+	y, ok := w.(int64)
+	if !ok {
+		return 0, 0, fmt.Errorf("expected a number but got %q", w)
+	}
+	return x, y, nil
+}
@@ -0,0 +1,136 @@
+package expr
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type mockEnv struct {
+	bs     []byte
+	cursor int64
+}
+
+func (e *mockEnv) Cursor() int64 {
+	return e.cursor
+}
+
+func (e *mockEnv) Length() int64 {
+	return int64(len(e.bs))
+}
+
+func (e *mockEnv) ReadAt(p []byte, offset int64) (int, error) {
+	return bytes.NewReader(e.bs).ReadAt(p, offset)
+}
+
+func TestEval(t *testing.T) {
+	env := &mockEnv{
+		bs:     []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff\x00\x00\x80\x00\x00\x00"),
+		cursor: 4,
+	}
+	testCases := []struct {
+		src      string
+		expected Value
+	}{
+		{"0x200+3*0x40", int64(0x2c0)},
+		{" ( 1 + 2 ) * 3 ", int64(9)},
+		{"1 << 4 >> 2", int64(4)},
+		{"0b1010 + 0o17 + 0XFF", int64(10 + 15 + 255)},
+		{"-7 % 3", int64(-1)},
+		{"~0", int64(-1)},
+		{".", int64(4)},
+		{". + 4", int64(8)},
+		{"$", int64(19)},
+		{"len()", int64(20)},
+		{"cursor()", int64(4)},
+		{"u8(0)", int64('M')},
+		{"u16le(0)", int64(0x5a4d)},
+		{"u16be(0)", int64(0x4d5a)},
+		{"u32le(.)", int64(3)},
+		{"u32be(.)", int64(0x03000000)},
+		{"u32le()", int64(3)},
+		{"i16le(12)", int64(-1)},
+		{"u16le(12)", int64(0xffff)},
+		{"i8(2)", int64(-0x70)},
+		{"u64le(8)", int64(0x0000ffff00000004)},
+		{"u64be(0x10)", nil},
+		{`"goto " . u32le(8)`, "goto 4"},
+		{`'a' .. "b\tc" . (1+2)`, "ab\tc3"},
+		{`"x" + 1`, nil},
+		{"1 / 0", nil},
+		{"foo(1)", nil},
+		{"foo", nil},
+		{"(1 + 2", nil},
+		{"1 2", nil},
+		{`"abc`, nil},
+		{"0x", nil},
+		{"len(1)", nil},
+	}
+	for _, testCase := range testCases {
+		got, err := Eval(testCase.src, env)
+		if testCase.expected == nil {
+			if err == nil {
+				t.Errorf("Eval(%q) should return an error but got %#v", testCase.src, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf("Eval(%q) should return %#v but got %#v", testCase.src, testCase.expected, got)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		v        Value
+		expected string
+	}{
+		{int64(60), "0x3c 60 0b111100"},
+		{int64(-2), "-0x2 -2 -0b10"},
+		{"goto 60", "goto 60"},
+	}
+	for _, testCase := range testCases {
+		if got := Format(testCase.v); got != testCase.expected {
+			t.Errorf("Format(%#v) should return %q but got %q", testCase.v, testCase.expected, got)
+		}
+	}
+}
+
+func TestParseInt(t *testing.T) {
+	testCases := []struct {
+		src   string
+		value int64
+		index int
+		err   string
+	}{
+		{"0x200+3*0x40", 0x2c0, 12, ""},
+		{"(1 + 2) * 3 ", 9, 11, ""},
+		{"1 << 4 >> 2,8", 4, 11, ""},
+		{"20w", 20, 2, ""},
+		{"0x1f+0x10g", 0x2f, 9, ""},
+		{"1+.", 0, 0, "unexpected character: ."},
+		{"(1+$)", 0, 0, "unexpected character: $)"},
+		{"10/0", 0, 0, "division by zero"},
+		{"1<<64", 0, 0, "invalid shift count: 64"},
+		{"0x", 0, 0, "invalid number: 0x"},
+		{"0xffffffffffffffff", 0, 0, "invalid number: 0xffffffffffffffff"},
+	}
+	for _, testCase := range testCases {
+		got, index, err := ParseInt([]rune(testCase.src), 0)
+		if testCase.err != "" {
+			if err == nil || err.Error() != testCase.err {
+				t.Errorf("ParseInt(%q) should return error %q but got %v", testCase.src, testCase.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if got != testCase.value || index != testCase.index {
+			t.Errorf("ParseInt(%q) should return %d, %d but got %d, %d", testCase.src, testCase.value, testCase.index, got, index)
+		}
+	}
+}
@@ -76,10 +76,20 @@ func (km *Manager) Press(k Key) event.Event {
 				return event.Event{Type: event.Nop}
 			case keysEq:
 				km.keys = nil
-				return event.Event{Type: ke.event, Count: count}
+				return event.Event{Type: ke.event, Count: count, Rune: lastRune(keys)}
 			}
 		}
 	}
 	km.keys = nil
 	return event.Event{Type: event.Nop}
 }
+
+func lastRune(keys []Key) rune {
+	if len(keys) == 0 {
+		return '\x00'
+	}
+	if rs := []rune(string(keys[len(keys)-1])); len(rs) == 1 {
+		return rs[0]
+	}
+	return '\x00'
+}
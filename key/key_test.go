@@ -69,3 +69,25 @@ func TestKeyManagerPressCount(t *testing.T) {
 		t.Errorf("pressing 37kj should emit event.CursorUp with count 37 but got: %d", e.Count)
 	}
 }
+
+func TestKeyManagerPressRune(t *testing.T) {
+	km := NewManager(true)
+	km.Register(event.SetMark, "m", "a")
+	km.Register(event.SetMark, "m", "b")
+	km.Register(event.CursorDown, "down")
+	e := km.Press("m")
+	if e.Type != event.Nop {
+		t.Errorf("pressing m should be nop but got: %d", e.Type)
+	}
+	e = km.Press("b")
+	if e.Type != event.SetMark {
+		t.Errorf("pressing m b should emit event.SetMark but got: %d", e.Type)
+	}
+	if e.Rune != 'b' {
+		t.Errorf("pressing m b should emit rune %q but got: %q", 'b', e.Rune)
+	}
+	e = km.Press("down")
+	if e.Rune != '\x00' {
+		t.Errorf("pressing down should emit rune %q but got: %q", '\x00', e.Rune)
+	}
+}
@@ -1,6 +1,10 @@
 package layout
 
-import "github.com/itchyny/bed/mathutil"
+import (
+	"math"
+
+	"github.com/itchyny/bed/mathutil"
+)
 
 // Layout represents the window layout.
 type Layout interface {
@@ -22,6 +26,11 @@ type Layout interface {
 	ActiveWindow() Window
 	Lookup(func(Window) bool) Window
 	Close() Layout
+	SetHeight(int) Layout
+	SetWidth(int) Layout
+	Equalize() Layout
+	Exchange(int) Layout
+	Rotate(bool) Layout
 }
 
 // Window holds the window index and it is active or not.
@@ -165,10 +174,37 @@ func (l Window) Close() Layout {
 	return l
 }
 
+// SetHeight sets the height of the active window.
+func (l Window) SetHeight(height int) Layout {
+	return l
+}
+
+// SetWidth sets the width of the active window.
+func (l Window) SetWidth(width int) Layout {
+	return l
+}
+
+// Equalize resets the ratios to split evenly.
+func (l Window) Equalize() Layout {
+	return l
+}
+
+// Exchange the active window with the next one in the same row or column,
+// or with the count-th one if the count is positive.
+func (l Window) Exchange(count int) Layout {
+	return l
+}
+
+// Rotate the windows in the same row or column as the active window.
+func (l Window) Rotate(downwards bool) Layout {
+	return l
+}
+
 // Horizontal holds two layout horizontally.
 type Horizontal struct {
 	Top    Layout
 	Bottom Layout
+	ratio  float64 // the ratio of the top height, or zero to split evenly
 	left   int
 	top    int
 	width  int
@@ -191,6 +227,7 @@ func (l Horizontal) Replace(index int) Layout {
 	return Horizontal{
 		Top:    l.Top.Replace(index),
 		Bottom: l.Bottom.Replace(index),
+		ratio:  l.ratio,
 		left:   l.left,
 		top:    l.top,
 		width:  l.width,
@@ -200,12 +237,18 @@ func (l Horizontal) Replace(index int) Layout {
 
 // Resize recalculates the position.
 func (l Horizontal) Resize(left, top, width, height int) Layout {
-	_, h1 := l.Top.Count()
-	_, h2 := l.Bottom.Count()
-	topHeight := height * h1 / (h1 + h2)
+	var topHeight int
+	if l.ratio > 0 && height > 1 {
+		topHeight = clamp(int(math.Round(float64(height)*l.ratio)), 1, height-1)
+	} else {
+		_, h1 := l.Top.Count()
+		_, h2 := l.Bottom.Count()
+		topHeight = height * h1 / (h1 + h2)
+	}
 	return Horizontal{
 		Top:    l.Top.Resize(left, top, width, topHeight),
 		Bottom: l.Bottom.Resize(left, top+topHeight, width, height-topHeight),
+		ratio:  l.ratio,
 		left:   left,
 		top:    top,
 		width:  width,
@@ -238,6 +281,7 @@ func (l Horizontal) SplitTop(index int) Layout {
 	return Horizontal{
 		Top:    l.Top.SplitTop(index),
 		Bottom: l.Bottom.SplitTop(index),
+		ratio:  l.ratio,
 	}
 }
 
@@ -246,6 +290,7 @@ func (l Horizontal) SplitBottom(index int) Layout {
 	return Horizontal{
 		Top:    l.Top.SplitBottom(index),
 		Bottom: l.Bottom.SplitBottom(index),
+		ratio:  l.ratio,
 	}
 }
 
@@ -254,6 +299,7 @@ func (l Horizontal) SplitLeft(index int) Layout {
 	return Horizontal{
 		Top:    l.Top.SplitLeft(index),
 		Bottom: l.Bottom.SplitLeft(index),
+		ratio:  l.ratio,
 	}
 }
 
@@ -262,6 +308,7 @@ func (l Horizontal) SplitRight(index int) Layout {
 	return Horizontal{
 		Top:    l.Top.SplitRight(index),
 		Bottom: l.Bottom.SplitRight(index),
+		ratio:  l.ratio,
 	}
 }
 
@@ -277,6 +324,7 @@ func (l Horizontal) Activate(i int) Layout {
 	return Horizontal{
 		Top:    l.Top.Activate(i),
 		Bottom: l.Bottom.Activate(i),
+		ratio:  l.ratio,
 		left:   l.left,
 		top:    l.top,
 		width:  l.width,
@@ -289,6 +337,7 @@ func (l Horizontal) ActivateFirst() Layout {
 	return Horizontal{
 		Top:    l.Top.ActivateFirst(),
 		Bottom: l.Bottom,
+		ratio:  l.ratio,
 		left:   l.left,
 		top:    l.top,
 		width:  l.width,
@@ -329,13 +378,53 @@ func (l Horizontal) Close() Layout {
 	return Horizontal{
 		Top:    l.Top.Close(),
 		Bottom: l.Bottom.Close(),
+		ratio:  l.ratio,
 	}
 }
 
+// SetHeight sets the height of the active window.
+func (l Horizontal) SetHeight(height int) Layout {
+	layout, _ := setHeight(l, height)
+	return layout
+}
+
+// SetWidth sets the width of the active window.
+func (l Horizontal) SetWidth(width int) Layout {
+	layout, _ := setWidth(l, width)
+	return layout
+}
+
+// Exchange the active window with the next one in the same row or column,
+// or with the count-th one if the count is positive.
+func (l Horizontal) Exchange(count int) Layout {
+	layout, _ := modifyActiveSiblings(l, func(ls []Layout, i int) []Layout {
+		return exchange(ls, i, count)
+	})
+	return layout
+}
+
+// Rotate the windows in the same row or column as the active window.
+func (l Horizontal) Rotate(downwards bool) Layout {
+	layout, _ := modifyActiveSiblings(l, func(ls []Layout, _ int) []Layout {
+		if downwards {
+			return append([]Layout{ls[len(ls)-1]}, ls[:len(ls)-1]...)
+		}
+		return append(ls[1:], ls[0])
+	})
+	return layout
+}
+
+// Equalize resets the ratios to split evenly.
+func (l Horizontal) Equalize() Layout {
+	l.Top, l.Bottom, l.ratio = l.Top.Equalize(), l.Bottom.Equalize(), 0
+	return l
+}
+
 // Vertical holds two layout vertically.
 type Vertical struct {
 	Left   Layout
 	Right  Layout
+	ratio  float64 // the ratio of the left width, or zero to split evenly
 	left   int
 	top    int
 	width  int
@@ -358,6 +447,7 @@ func (l Vertical) Replace(index int) Layout {
 	return Vertical{
 		Left:   l.Left.Replace(index),
 		Right:  l.Right.Replace(index),
+		ratio:  l.ratio,
 		left:   l.left,
 		top:    l.top,
 		width:  l.width,
@@ -367,14 +457,20 @@ func (l Vertical) Replace(index int) Layout {
 
 // Resize recalculates the position.
 func (l Vertical) Resize(left, top, width, height int) Layout {
-	w1, _ := l.Left.Count()
-	w2, _ := l.Right.Count()
-	leftWidth := width * w1 / (w1 + w2)
+	var leftWidth int
+	if l.ratio > 0 && width > 2 {
+		leftWidth = clamp(int(math.Round(float64(width)*l.ratio)), 1, width-2)
+	} else {
+		w1, _ := l.Left.Count()
+		w2, _ := l.Right.Count()
+		leftWidth = width * w1 / (w1 + w2)
+	}
 	return Vertical{
 		Left: l.Left.Resize(left, top, leftWidth, height),
 		Right: l.Right.Resize(
 			mathutil.MinInt(left+leftWidth+1, left+width), top,
 			mathutil.MaxInt(width-leftWidth-1, 0), height),
+		ratio:  l.ratio,
 		left:   left,
 		top:    top,
 		width:  width,
@@ -407,6 +503,7 @@ func (l Vertical) SplitTop(index int) Layout {
 	return Vertical{
 		Left:  l.Left.SplitTop(index),
 		Right: l.Right.SplitTop(index),
+		ratio: l.ratio,
 	}
 }
 
@@ -415,6 +512,7 @@ func (l Vertical) SplitBottom(index int) Layout {
 	return Vertical{
 		Left:  l.Left.SplitBottom(index),
 		Right: l.Right.SplitBottom(index),
+		ratio: l.ratio,
 	}
 }
 
@@ -423,6 +521,7 @@ func (l Vertical) SplitLeft(index int) Layout {
 	return Vertical{
 		Left:  l.Left.SplitLeft(index),
 		Right: l.Right.SplitLeft(index),
+		ratio: l.ratio,
 	}
 }
 
@@ -431,6 +530,7 @@ func (l Vertical) SplitRight(index int) Layout {
 	return Vertical{
 		Left:  l.Left.SplitRight(index),
 		Right: l.Right.SplitRight(index),
+		ratio: l.ratio,
 	}
 }
 
@@ -446,6 +546,7 @@ func (l Vertical) Activate(i int) Layout {
 	return Vertical{
 		Left:   l.Left.Activate(i),
 		Right:  l.Right.Activate(i),
+		ratio:  l.ratio,
 		left:   l.left,
 		top:    l.top,
 		width:  l.width,
@@ -458,6 +559,7 @@ func (l Vertical) ActivateFirst() Layout {
 	return Vertical{
 		Left:   l.Left.ActivateFirst(),
 		Right:  l.Right,
+		ratio:  l.ratio,
 		left:   l.left,
 		top:    l.top,
 		width:  l.width,
@@ -498,5 +600,182 @@ func (l Vertical) Close() Layout {
 	return Vertical{
 		Left:  l.Left.Close(),
 		Right: l.Right.Close(),
+		ratio: l.ratio,
 	}
 }
+
+// SetHeight sets the height of the active window.
+func (l Vertical) SetHeight(height int) Layout {
+	layout, _ := setHeight(l, height)
+	return layout
+}
+
+// SetWidth sets the width of the active window.
+func (l Vertical) SetWidth(width int) Layout {
+	layout, _ := setWidth(l, width)
+	return layout
+}
+
+// Exchange the active window with the next one in the same row or column,
+// or with the count-th one if the count is positive.
+func (l Vertical) Exchange(count int) Layout {
+	layout, _ := modifyActiveSiblings(l, func(ls []Layout, i int) []Layout {
+		return exchange(ls, i, count)
+	})
+	return layout
+}
+
+// Rotate the windows in the same row or column as the active window.
+func (l Vertical) Rotate(downwards bool) Layout {
+	layout, _ := modifyActiveSiblings(l, func(ls []Layout, _ int) []Layout {
+		if downwards {
+			return append([]Layout{ls[len(ls)-1]}, ls[:len(ls)-1]...)
+		}
+		return append(ls[1:], ls[0])
+	})
+	return layout
+}
+
+// Equalize resets the ratios to split evenly.
+func (l Vertical) Equalize() Layout {
+	l.Left, l.Right, l.ratio = l.Left.Equalize(), l.Right.Equalize(), 0
+	return l
+}
+
+// setHeight updates the ratio of the nearest horizontal split
+// containing the active window.
+func setHeight(l Layout, height int) (Layout, bool) {
+	var ok bool
+	switch l := l.(type) {
+	case Horizontal:
+		if l.Top.ActiveWindow().Index >= 0 {
+			if l.Top, ok = setHeight(l.Top, height); !ok && l.height > 1 {
+				l.ratio = float64(clamp(height, 1, l.height-1)) / float64(l.height)
+			}
+		} else if l.Bottom.ActiveWindow().Index >= 0 {
+			if l.Bottom, ok = setHeight(l.Bottom, height); !ok && l.height > 1 {
+				l.ratio = float64(clamp(l.height-height, 1, l.height-1)) / float64(l.height)
+			}
+		} else {
+			return l, false
+		}
+		return l, true
+	case Vertical:
+		if l.Left.ActiveWindow().Index >= 0 {
+			l.Left, ok = setHeight(l.Left, height)
+		} else {
+			l.Right, ok = setHeight(l.Right, height)
+		}
+		return l, ok
+	}
+	return l, false
+}
+
+// setWidth updates the ratio of the nearest vertical split
+// containing the active window.
+func setWidth(l Layout, width int) (Layout, bool) {
+	var ok bool
+	switch l := l.(type) {
+	case Horizontal:
+		if l.Top.ActiveWindow().Index >= 0 {
+			l.Top, ok = setWidth(l.Top, width)
+		} else {
+			l.Bottom, ok = setWidth(l.Bottom, width)
+		}
+		return l, ok
+	case Vertical:
+		if l.Left.ActiveWindow().Index >= 0 {
+			if l.Left, ok = setWidth(l.Left, width); !ok && l.width > 2 {
+				l.ratio = float64(clamp(width, 1, l.width-2)) / float64(l.width)
+			}
+		} else if l.Right.ActiveWindow().Index >= 0 {
+			if l.Right, ok = setWidth(l.Right, width); !ok && l.width > 2 {
+				l.ratio = float64(clamp(l.width-width-1, 1, l.width-2)) / float64(l.width)
+			}
+		} else {
+			return l, false
+		}
+		return l, true
+	}
+	return l, false
+}
+
+// modifyActiveSiblings applies the function to the layouts in the innermost
+// row or column containing the active window, which is at the given index.
+func modifyActiveSiblings(l Layout, f func([]Layout, int) []Layout) (Layout, bool) {
+	if _, ok := l.(Window); ok || l.ActiveWindow().Index < 0 {
+		return l, false
+	}
+	ls := siblings(l, l)
+	for i, m := range ls {
+		if w, ok := m.(Window); ok && w.Active {
+			l, _ = rebuild(l, l, f(ls, i))
+			return l, true
+		}
+		if m, ok := modifyActiveSiblings(m, f); ok {
+			ls[i] = m
+			l, _ = rebuild(l, l, ls)
+			return l, true
+		}
+	}
+	return l, false
+}
+
+// siblings flattens the splits of the same direction as the root.
+func siblings(root, l Layout) []Layout {
+	switch l := l.(type) {
+	case Horizontal:
+		if _, ok := root.(Horizontal); ok {
+			return append(siblings(root, l.Top), siblings(root, l.Bottom)...)
+		}
+	case Vertical:
+		if _, ok := root.(Vertical); ok {
+			return append(siblings(root, l.Left), siblings(root, l.Right)...)
+		}
+	}
+	return []Layout{l}
+}
+
+// rebuild replaces the siblings keeping the shape of the splits and the ratios.
+func rebuild(root, l Layout, ls []Layout) (Layout, []Layout) {
+	switch m := l.(type) {
+	case Horizontal:
+		if _, ok := root.(Horizontal); ok {
+			m.Top, ls = rebuild(root, m.Top, ls)
+			m.Bottom, ls = rebuild(root, m.Bottom, ls)
+			return m, ls
+		}
+	case Vertical:
+		if _, ok := root.(Vertical); ok {
+			m.Left, ls = rebuild(root, m.Left, ls)
+			m.Right, ls = rebuild(root, m.Right, ls)
+			return m, ls
+		}
+	}
+	return ls[0], ls[1:]
+}
+
+// exchange swaps the active window at the index with the next one,
+// or with the count-th one, and activates the window in place of it.
+func exchange(ls []Layout, i, count int) []Layout {
+	j := i + 1
+	if count > 0 {
+		j = count - 1
+	} else if j == len(ls) {
+		j = i - 1
+	}
+	if j < 0 || len(ls) <= j || i == j {
+		return ls
+	}
+	if w, ok := ls[j].(Window); ok {
+		v := ls[i].(Window)
+		v.Active, w.Active = false, true
+		ls[i], ls[j] = v, w
+	}
+	ls[i], ls[j] = ls[j], ls[i]
+	return ls
+}
+
+func clamp(x, min, max int) int {
+	return mathutil.MaxInt(mathutil.MinInt(x, max), min)
+}
@@ -305,3 +305,135 @@ func TestLayout(t *testing.T) {
 		t.Errorf("Height() should be %+v but layout %+v", 10, layout.Height())
 	}
 }
+
+func TestLayoutRatio(t *testing.T) {
+	layout := NewLayout(0).SplitTop(1).SplitLeft(2).Resize(0, 0, 20, 20)
+
+	check := func(index, left, top, width, height int) {
+		t.Helper()
+		expected := Window{Index: index, left: left, top: top, width: width, height: height}
+		got := layout.Lookup(func(l Window) bool { return l.Index == index })
+		got.Active = false
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Lookup(Index == %d) should be %+v but got %+v", index, expected, got)
+		}
+	}
+	check(2, 0, 0, 10, 10)
+	check(1, 11, 0, 9, 10)
+	check(0, 0, 10, 20, 10)
+
+	layout = layout.SetHeight(14).Resize(0, 0, 20, 20)
+	check(2, 0, 0, 10, 14)
+	check(1, 11, 0, 9, 14)
+	check(0, 0, 14, 20, 6)
+
+	layout = layout.SetWidth(5).Resize(0, 0, 20, 20)
+	check(2, 0, 0, 5, 14)
+	check(1, 6, 0, 14, 14)
+
+	layout = layout.Activate(0).SetHeight(4).Resize(0, 0, 20, 20)
+	check(2, 0, 0, 5, 16)
+	check(0, 0, 16, 20, 4)
+
+	layout = layout.SetHeight(100).Resize(0, 0, 20, 20)
+	check(2, 0, 0, 5, 1)
+	check(0, 0, 1, 20, 19)
+
+	layout = layout.SetHeight(4).Activate(1).SplitBottom(3).Resize(0, 0, 20, 20)
+	check(1, 6, 0, 14, 8)
+	check(3, 6, 8, 14, 8)
+	check(0, 0, 16, 20, 4)
+
+	layout = layout.SetWidth(16).Resize(0, 0, 20, 20)
+	check(2, 0, 0, 3, 16)
+	check(3, 4, 8, 16, 8)
+
+	layout = layout.Resize(0, 0, 40, 40)
+	check(2, 0, 0, 6, 32)
+	check(3, 7, 16, 33, 16)
+	check(0, 0, 32, 40, 8)
+
+	layout = layout.Resize(0, 0, 1, 1)
+	check(2, 0, 0, 0, 0)
+	check(3, 1, 0, 0, 0)
+	check(0, 0, 0, 1, 1)
+
+	layout = layout.Resize(0, 0, 0, 0)
+	check(2, 0, 0, 0, 0)
+	check(3, 0, 0, 0, 0)
+	check(0, 0, 0, 0, 0)
+
+	layout = layout.Equalize().Resize(0, 0, 20, 20)
+	check(2, 0, 0, 10, 13)
+	check(1, 11, 0, 9, 6)
+	check(3, 11, 6, 9, 7)
+	check(0, 0, 13, 20, 7)
+}
+
+func TestLayoutExchangeRotate(t *testing.T) {
+	layout := NewLayout(0).SplitBottom(1).SplitBottom(2).SplitLeft(3)
+
+	layout = layout.Exchange(0)
+	expected := Layout(Horizontal{
+		Top: Window{Index: 0},
+		Bottom: Horizontal{
+			Top: Window{Index: 1},
+			Bottom: Vertical{
+				Left:  Window{Index: 2, Active: true},
+				Right: Window{Index: 3},
+			},
+		},
+	})
+	if !reflect.DeepEqual(layout, expected) {
+		t.Errorf("layout should be %#v but got %#v", expected, layout)
+	}
+
+	layout = layout.Activate(0).Rotate(true)
+	expected = Horizontal{
+		Top: Vertical{
+			Left:  Window{Index: 2},
+			Right: Window{Index: 3},
+		},
+		Bottom: Horizontal{
+			Top:    Window{Index: 0, Active: true},
+			Bottom: Window{Index: 1},
+		},
+	}
+	if !reflect.DeepEqual(layout, expected) {
+		t.Errorf("layout should be %#v but got %#v", expected, layout)
+	}
+
+	layout = layout.Rotate(false).Activate(1).Exchange(0)
+	expected = Horizontal{
+		Top: Window{Index: 0},
+		Bottom: Horizontal{
+			Top: Vertical{
+				Left:  Window{Index: 2},
+				Right: Window{Index: 3},
+			},
+			Bottom: Window{Index: 1, Active: true},
+		},
+	}
+	if !reflect.DeepEqual(layout, expected) {
+		t.Errorf("layout should be %#v but got %#v", expected, layout)
+	}
+
+	layout = layout.Exchange(1)
+	expected = Horizontal{
+		Top: Window{Index: 1},
+		Bottom: Horizontal{
+			Top: Vertical{
+				Left:  Window{Index: 2},
+				Right: Window{Index: 3},
+			},
+			Bottom: Window{Index: 0, Active: true},
+		},
+	}
+	if !reflect.DeepEqual(layout, expected) {
+		t.Errorf("layout should be %#v but got %#v", expected, layout)
+	}
+
+	if got := layout.Exchange(5); !reflect.DeepEqual(got, expected) {
+		t.Errorf("layout should be %#v but got %#v", expected, got)
+	}
+}
@@ -0,0 +1,37 @@
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Command returns the command which runs the command line in the shell.
+func Command(cmdline string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/c", cmdline)
+	}
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "sh"
+	}
+	return exec.Command(shell, "-c", cmdline)
+}
+
+// Filter runs the command line with the input and returns the output.
+// The error output of the command is reported in the error.
+func Filter(cmdline string, input []byte) ([]byte, error) {
+	cmd := Command(cmdline)
+	cmd.Stdin = bytes.NewReader(input)
+	output, err := cmd.Output()
+	if err != nil {
+		if err, ok := err.(*exec.ExitError); ok && len(bytes.TrimSpace(err.Stderr)) > 0 {
+			return nil, fmt.Errorf("%s: %s", cmdline, strings.TrimSpace(string(err.Stderr)))
+		}
+		return nil, fmt.Errorf("%s: %s", cmdline, err)
+	}
+	return output, nil
+}
@@ -0,0 +1,28 @@
+package shell
+
+import "testing"
+
+func TestFilter(t *testing.T) {
+	testCases := []struct {
+		cmdline  string
+		input    string
+		expected string
+		err      string
+	}{
+		{"cat", "Hello, world!", "Hello, world!", ""},
+		{"tr a-z A-Z", "Hello, world!", "HELLO, WORLD!", ""},
+		{"tr -d l | cat", "Hello, world!", "Heo, word!", ""},
+		{"cat >/dev/null", "Hello, world!", "", ""},
+		{"echo foo >&2; exit 1", "", "", "echo foo >&2; exit 1: foo"},
+		{"exit 2", "", "", "exit 2: exit status 2"},
+	}
+	for _, tc := range testCases {
+		got, err := Filter(tc.cmdline, []byte(tc.input))
+		if tc.err == "" && err != nil || tc.err != "" && (err == nil || err.Error() != tc.err) {
+			t.Errorf("Filter(%q) should report error %q but got %v", tc.cmdline, tc.err, err)
+		}
+		if string(got) != tc.expected {
+			t.Errorf("Filter(%q) should be %q but got %q", tc.cmdline, tc.expected, string(got))
+		}
+	}
+}
@@ -11,6 +11,8 @@ type State struct {
 	PrevMode          mode.Mode
 	WindowStates      map[int]*WindowState
 	Layout            layout.Layout
+	TabPages          []string
+	TabIndex          int
 	Cmdline           []rune
 	CmdlineCursor     int
 	CompletionResults []string
@@ -23,6 +25,8 @@ type State struct {
 // WindowState holds the state of one window.
 type WindowState struct {
 	Name          string
+	Modified      bool
+	ReadOnly      bool
 	Width         int
 	Offset        int64
 	Cursor        int64
@@ -34,6 +38,7 @@ type WindowState struct {
 	PendingByte   byte
 	VisualStart   int64
 	EditedIndices []int64
+	DiffIndices   []int64
 	FocusText     bool
 }
 
@@ -81,12 +81,30 @@ func (ui *Tui) setLine(line int, offset int, str string, style tcell.Style) {
 func (ui *Tui) Redraw(s state.State) error {
 	ui.mode = s.Mode
 	ui.screen.Clear()
+	ui.drawTabline(s)
 	ui.drawWindows(s.WindowStates, s.Layout)
 	ui.drawCmdline(s)
 	ui.screen.Show()
 	return nil
 }
 
+func (ui *Tui) drawTabline(s state.State) {
+	if len(s.TabPages) <= 1 {
+		return
+	}
+	width, _ := ui.Size()
+	ui.setLine(0, 0, strings.Repeat(" ", width), tcell.StyleDefault.Reverse(true))
+	var offset int
+	for i, name := range s.TabPages {
+		style := tcell.StyleDefault.Reverse(true)
+		if i == s.TabIndex {
+			style = tcell.StyleDefault.Bold(true)
+		}
+		ui.setLine(0, offset, " "+name+" ", style)
+		offset += runewidth.StringWidth(name) + 2
+	}
+}
+
 func (ui *Tui) drawWindows(windowStates map[int]*state.WindowState, l layout.Layout) {
 	switch l := l.(type) {
 	case layout.Window:
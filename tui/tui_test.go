@@ -308,6 +308,59 @@ func TestTuiVerticalSplit(t *testing.T) {
 	}
 }
 
+func TestTuiTabline(t *testing.T) {
+	ui := NewTui()
+	eventCh := make(chan event.Event)
+	screen := tcell.NewSimulationScreen("")
+	if err := ui.initForTest(eventCh, screen); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(90, 20)
+	width, height := screen.Size()
+	go ui.Run(mockKeyManager())
+
+	s := state.State{
+		WindowStates: map[int]*state.WindowState{
+			0: &state.WindowState{
+				Name:   "test0",
+				Width:  16,
+				Offset: 0,
+				Cursor: 0,
+				Bytes:  []byte("Test window 0." + strings.Repeat("\x00", 16*16-14)),
+				Size:   16 * 16,
+				Length: 600,
+				Mode:   mode.Normal,
+			},
+		},
+		Layout:   layout.NewLayout(0).Resize(0, 1, width, height-2),
+		TabPages: []string{"foo.bin", "2 bar.bin", "[No Name]"},
+		TabIndex: 1,
+	}
+	if err := ui.Redraw(s); err != nil {
+		t.Errorf("ui.Redraw should return nil but got: %v", err)
+	}
+
+	got := getContents(screen)
+	if expected := " foo.bin  2 bar.bin  [No Name] "; !strings.HasPrefix(got, expected) {
+		t.Errorf("screen should start with %q but got\n%v", expected, got)
+	}
+	shouldContain(t, screen, []string{
+		"        |  0  1  2  3  4  5  6  7  8  9  a  b  c  d  e  f |",
+		" 000000 | 54 65 73 74 20 77 69 6e 64 6f 77 20 30 2e 00 00 | Test window 0... #",
+	})
+	if lines := strings.Split(got, "\n"); !strings.HasPrefix(lines[1], "        |  0  1  2") {
+		t.Errorf("windows should be drawn below the tabline but got\n%v", got)
+	}
+
+	x, y, _ := screen.GetCursor()
+	if x != 10 || y != 2 {
+		t.Errorf("cursor position should be (%d, %d) but got (%d, %d)", 10, 2, x, y)
+	}
+	if err := ui.Close(); err != nil {
+		t.Errorf("ui.Close should return nil but got %v", err)
+	}
+}
+
 func TestTuiCmdline(t *testing.T) {
 	ui := NewTui()
 	eventCh := make(chan event.Event)
@@ -399,3 +452,24 @@ func TestTuiCmdlineCompletionCandidates(t *testing.T) {
 		t.Errorf("ui.Close should return nil but got %v", err)
 	}
 }
+
+func TestTuiWindowDiffIndices(t *testing.T) {
+	ui := &tuiWindow{}
+	s := &state.WindowState{
+		Width:       4,
+		Offset:      4,
+		Bytes:       []byte("abcdefgh"),
+		Size:        8,
+		Length:      12,
+		VisualStart: -1,
+		DiffIndices: []int64{2, 5, 7, 8, 10, 11},
+	}
+	_, styles := ui.bytesArray(2, 4, s)
+	expected := []bool{true, false, false, true, false, false, true, false}
+	style := tcell.Style(0).Background(tcell.ColorDarkRed)
+	for i, diff := range expected {
+		if got := styles[i/4][i%4] == style; got != diff {
+			t.Errorf("byte at %d should be highlighted %v but got %v", 4+i, diff, got)
+		}
+	}
+}
@@ -91,7 +91,7 @@ func (ui *tuiWindow) bytesArray(height, width int, s *state.WindowState) ([][]by
 	if height <= 0 {
 		return nil, nil
 	}
-	eis := s.EditedIndices
+	eis, dis := s.EditedIndices, s.DiffIndices
 	bytes := make([][]byte, height)
 	styles := make([][]tcell.Style, height)
 	color := tcell.ColorLightSeaGreen
@@ -117,6 +117,12 @@ func (ui *tuiWindow) bytesArray(height, width int, s *state.WindowState) ([][]by
 			} else if 0 < len(eis) && eis[1] <= pos {
 				eis = eis[2:]
 			}
+			for 0 < len(dis) && dis[1] <= pos {
+				dis = dis[2:]
+			}
+			if 0 < len(dis) && dis[0] <= pos {
+				styles[i][j] = styles[i][j].Background(tcell.ColorDarkRed)
+			}
 			if s.VisualStart >= 0 && s.Cursor < s.Length &&
 				(s.VisualStart <= pos && pos <= s.Cursor ||
 					s.Cursor <= pos && pos <= s.VisualStart) {
@@ -169,6 +175,12 @@ func (ui *tuiWindow) drawFooter(s *state.WindowState, offsetStyleWidth int) {
 	if name == "" {
 		name = "[No name]"
 	}
+	if s.Modified {
+		name += " [+]"
+	}
+	if s.ReadOnly {
+		name += " [RO]"
+	}
 	left := fmt.Sprintf(" %s%s : 0x%02x : '%s'",
 		prettyMode(s.Mode), name, s.Bytes[j], prettyRune(s.Bytes[j]))
 	right := fmt.Sprintf("%d/%d : "+offsetStyle+"/"+offsetStyle+" : %.2f%% ",
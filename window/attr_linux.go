@@ -0,0 +1,47 @@
+// +build linux
+
+package window
+
+import (
+	"os"
+	"strings"
+	"syscall"
+)
+
+// copyAttrs copies the owner and the extended attributes, including the
+// access control lists, of the file where possible.
+func copyAttrs(src, dst string) {
+	copyOwner(src, dst)
+	size, err := syscall.Listxattr(src, nil)
+	if err != nil || size <= 0 {
+		return
+	}
+	buf := make([]byte, size)
+	if size, err = syscall.Listxattr(src, buf); err != nil {
+		return
+	}
+	for _, name := range strings.Split(strings.TrimRight(string(buf[:size]), "\x00"), "\x00") {
+		size, err := syscall.Getxattr(src, name, nil)
+		if err != nil {
+			continue
+		}
+		value := make([]byte, size)
+		if size, err = syscall.Getxattr(src, name, value); err != nil {
+			continue
+		}
+		_ = syscall.Setxattr(dst, name, value[:size], 0)
+	}
+}
+
+func copyOwner(src, dst string) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		if os.Chown(dst, int(st.Uid), int(st.Gid)) == nil {
+			// changing the owner clears the setuid and setgid bits
+			_ = os.Chmod(dst, info.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky))
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// +build linux
+
+package window
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestCopyAttrs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bed-test-copy-attrs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	for _, name := range []string{src, dst} {
+		if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := syscall.Setxattr(src, "user.bed", []byte("test"), 0); err != nil {
+		t.Skipf("extended attributes are not supported: %v", err)
+	}
+	copyAttrs(src, dst)
+	value := make([]byte, 16)
+	n, err := syscall.Getxattr(dst, "user.bed", value)
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "test"; string(value[:n]) != expected {
+		t.Errorf("extended attribute should be %q but got %q", expected, string(value[:n]))
+	}
+}
@@ -0,0 +1,22 @@
+// +build !windows,!linux
+
+package window
+
+import (
+	"os"
+	"syscall"
+)
+
+// copyAttrs copies the owner of the file where possible.
+func copyAttrs(src, dst string) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		if os.Chown(dst, int(st.Uid), int(st.Gid)) == nil {
+			// changing the owner clears the setuid and setgid bits
+			_ = os.Chmod(dst, info.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky))
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package window
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// backupName returns the name of the backup file of the file. In the
+// numbered mode, the name is file.~N~ with the next number of the versions.
+// When the backupdir option ends with two path separators, the full path of
+// the file is used as the name with the separators replaced by %, so that
+// the files with the same name in different directories do not collide.
+func (m *Manager) backupName(path string) (string, error) {
+	dir, base := filepath.Dir(path), filepath.Base(path)
+	if m.options.backupDir != "." {
+		var err error
+		if dir, err = homedir.Expand(m.options.backupDir); err != nil {
+			return "", err
+		}
+		sep := string(filepath.Separator)
+		if strings.HasSuffix(dir, sep+sep) {
+			if path, err = filepath.Abs(path); err != nil {
+				return "", err
+			}
+			base = strings.Replace(path, sep, "%", -1)
+		}
+	}
+	if !m.options.backupNumbered {
+		return filepath.Join(dir, base+m.options.backupExt), nil
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var number int
+	for _, fi := range fis {
+		name := fi.Name()
+		if len(name) > len(base)+3 && strings.HasPrefix(name, base+".~") && strings.HasSuffix(name, "~") {
+			if n, err := strconv.Atoi(name[len(base)+2 : len(name)-1]); err == nil && n > number {
+				number = n
+			}
+		}
+	}
+	return filepath.Join(dir, base+".~"+strconv.Itoa(number+1)+"~"), nil
+}
+
+// backup keeps the current version of the file as the backup file. When the
+// file is going to be replaced by renaming, a hard link to it is enough.
+// Otherwise the file is copied.
+func (m *Manager) backup(path string, link bool) (string, error) {
+	name, err := m.backupName(path)
+	if err != nil {
+		return "", err
+	}
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if link && os.Link(path, name) == nil {
+		return name, nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return "", err
+	}
+	dst, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(name)
+		return "", err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(name)
+		return "", err
+	}
+	copyAttrs(path, name)
+	return name, nil
+}
+
+// backupForRename keeps the backup file before renaming the temporary file
+// over the file with the backup option. The writebackup option does not make
+// a backup here because renaming replaces the file at once.
+func (m *Manager) backupForRename(path string, force bool) error {
+	if !m.options.backup {
+		return nil
+	}
+	if _, err := m.backup(path, true); err != nil && !force {
+		return fmt.Errorf("cannot make backup file: %s (add ! to override)", err)
+	}
+	return nil
+}
+
+// backupForOverwrite copies the file before overwriting it in place with the
+// backup or writebackup option. The returned name is passed to removeBackup.
+func (m *Manager) backupForOverwrite(path string, force bool) (string, error) {
+	if !m.options.backup && !m.options.writeBackup {
+		return "", nil
+	}
+	name, err := m.backup(path, false)
+	if err != nil && !force {
+		return "", fmt.Errorf("cannot make backup file: %s (add ! to override)", err)
+	}
+	return name, nil
+}
+
+// removeBackup removes the backup file made only for writing safely,
+// which is called after the file is written successfully.
+func (m *Manager) removeBackup(name string) {
+	if name != "" && !m.options.backup {
+		os.Remove(name)
+	}
+}
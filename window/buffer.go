@@ -0,0 +1,189 @@
+package window
+
+import (
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/itchyny/bed/buffer"
+	"github.com/itchyny/bed/history"
+	"github.com/itchyny/bed/mathutil"
+)
+
+// fileBuffer is the buffer shared by the windows viewing the same file.
+// The windows share the mutex so that the edits are serialized.
+type fileBuffer struct {
+	id          int
+	buffer      *buffer.Buffer
+	history     *history.History
+	filename    string
+	name        string
+	changedTick uint64
+	savedTick   uint64
+	stat        os.FileInfo
+	warnedStat  os.FileInfo
+	swap        *swapFile
+	swapExists  bool
+	marks       map[rune]int64
+	options     options
+	mu          *sync.Mutex
+}
+
+func newFileBuffer(r readAtSeeker, filename string, name string) (*fileBuffer, error) {
+	buffer := buffer.NewBuffer(r)
+	if _, err := buffer.Len(); err != nil {
+		return nil, err
+	}
+	history := history.NewHistory()
+	history.Push(buffer, 0, 0)
+	return &fileBuffer{
+		buffer:   buffer,
+		history:  history,
+		filename: filename,
+		name:     name,
+		marks:    make(map[rune]int64),
+		options:  defaultOptions(),
+		mu:       new(sync.Mutex),
+	}, nil
+}
+
+// checkModifiable returns an error if the buffer cannot be changed.
+func (b *fileBuffer) checkModifiable() error {
+	if !b.options.modifiable {
+		return errors.New("cannot make changes, 'modifiable' is off")
+	}
+	if b.options.readonly {
+		return errors.New("cannot make changes, 'readonly' is set")
+	}
+	return nil
+}
+
+// modified reports whether the buffer is changed after the last write.
+func (b *fileBuffer) modified() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.changedTick != b.savedTick
+}
+
+// updateStat records the status of the file, which is used to detect
+// the changes on the disk made by other processes.
+func (b *fileBuffer) updateStat() {
+	var stat os.FileInfo
+	if b.filename != "" {
+		stat, _ = os.Stat(b.filename)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stat, b.warnedStat = stat, nil
+}
+
+// changedOnDisk reports whether the file is changed on the disk after the
+// buffer read or wrote it. It also returns the current status of the file.
+func (b *fileBuffer) changedOnDisk() (os.FileInfo, bool) {
+	b.mu.Lock()
+	filename, stat := b.filename, b.stat
+	b.mu.Unlock()
+	if filename == "" || stat == nil {
+		return nil, false
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, false
+	}
+	return info, !sameStat(stat, info)
+}
+
+func sameStat(x, y os.FileInfo) bool {
+	return x != nil && y != nil && os.SameFile(x, y) &&
+		x.Size() == y.Size() && x.ModTime().Equal(y.ModTime())
+}
+
+func (b *fileBuffer) insert(offset int64, c byte) {
+	b.buffer.Insert(offset, c)
+	b.changedTick++
+	b.swap.write(swapInsert, offset, int64(c))
+	for name, pos := range b.marks {
+		if pos >= offset {
+			b.marks[name] = pos + 1
+		}
+	}
+}
+
+func (b *fileBuffer) replace(offset int64, c byte) {
+	b.buffer.Replace(offset, c)
+	b.changedTick++
+	b.swap.write(swapReplace, offset, int64(c))
+}
+
+func (b *fileBuffer) delete(offset int64) {
+	b.buffer.Delete(offset)
+	b.changedTick++
+	b.swap.write(swapDelete, offset)
+	for name, pos := range b.marks {
+		if pos > offset {
+			b.marks[name] = pos - 1
+		}
+	}
+}
+
+func (b *fileBuffer) insertBytes(offset int64, bs []byte) {
+	b.buffer.InsertBytes(offset, bs)
+	b.changedTick++
+	b.swap.writeBytes(swapInsertBytes, offset, bs)
+	for name, pos := range b.marks {
+		if pos >= offset {
+			b.marks[name] = pos + int64(len(bs))
+		}
+	}
+}
+
+func (b *fileBuffer) replaceBytes(offset int64, bs []byte) {
+	b.buffer.ReplaceBytes(offset, bs)
+	b.changedTick++
+	b.swap.writeBytes(swapReplaceBytes, offset, bs)
+}
+
+func (b *fileBuffer) deleteBytes(offset, count int64) {
+	b.buffer.DeleteBytes(offset, count)
+	b.changedTick++
+	b.swap.write(swapDeleteBytes, offset, count)
+	for name, pos := range b.marks {
+		if pos > offset {
+			b.marks[name] = mathutil.MaxInt64(pos-count, offset)
+		}
+	}
+}
+
+func (b *fileBuffer) setBuffer(buffer *buffer.Buffer) {
+	b.buffer = buffer
+	b.changedTick++
+}
+
+// push adds the buffer to the history.
+func (b *fileBuffer) push(offset, cursor int64) {
+	b.history.Push(b.buffer, offset, cursor)
+	b.swap.write(swapPush, offset, cursor)
+	b.swap.flush()
+}
+
+// undo restores the previous buffer in the history.
+func (b *fileBuffer) undo() (int64, int64, bool) {
+	buffer, _, offset, cursor := b.history.Undo()
+	if buffer == nil {
+		return 0, 0, false
+	}
+	b.setBuffer(buffer)
+	b.swap.write(swapUndo)
+	return offset, cursor, true
+}
+
+// redo restores the next buffer in the history.
+func (b *fileBuffer) redo() (int64, int64, bool) {
+	buffer, offset, cursor := b.history.Redo()
+	if buffer == nil {
+		return 0, 0, false
+	}
+	b.setBuffer(buffer)
+	b.swap.write(swapRedo)
+	return offset, cursor, true
+}
@@ -0,0 +1,342 @@
+package window
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/itchyny/bed/buffer"
+	"github.com/itchyny/bed/diff"
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/mathutil"
+	"github.com/itchyny/bed/state"
+)
+
+const (
+	maxDiffAlignSize = 1 << 20
+	maxDiffAlignCost = 1024
+)
+
+// diffState holds the differences of the two windows in diff mode,
+// which are recomputed in background when either buffer is changed.
+type diffState struct {
+	a, b         *fileBuffer
+	aTick, bTick uint64
+	align        bool
+	hunks        []diff.Hunk
+	err          error
+	done         chan struct{}
+}
+
+func (m *Manager) diffThis(e event.Event) error {
+	if len(e.Arg) > 0 {
+		return fmt.Errorf("too many arguments for %s", e.CmdName)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setDiff(m.windows[m.windowIndex], true)
+	return nil
+}
+
+func (m *Manager) diffOff(e event.Event) error {
+	if len(e.Arg) > 0 {
+		return fmt.Errorf("too many arguments for %s", e.CmdName)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setDiff(m.windows[m.windowIndex], false)
+	return nil
+}
+
+// Diff sets the diff mode to all the windows in the active tab page.
+func (m *Manager) Diff() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.layout.Collect() {
+		m.setDiff(m.windows[i], true)
+	}
+}
+
+// setDiff sets the diff mode of the window,
+// which binds the scrolling with the other window.
+func (m *Manager) setDiff(window *window, diff bool) {
+	window.mu.Lock()
+	defer window.mu.Unlock()
+	window.options.diff = diff
+	window.options.scrollBind = diff
+}
+
+// diffWindows returns the indices of the first two windows
+// in diff mode in the active tab page.
+func (m *Manager) diffWindows() (int, int, bool) {
+	var indices []int
+	for i := range m.layout.Collect() {
+		window := m.windows[i]
+		window.mu.Lock()
+		if window.options.diff {
+			indices = append(indices, i)
+		}
+		window.mu.Unlock()
+	}
+	if len(indices) < 2 {
+		return 0, 0, false
+	}
+	sort.Ints(indices)
+	return indices[0], indices[1], true
+}
+
+// updateDiff starts computing the differences of the windows in diff mode
+// if the buffers are changed after the last computation. The computation
+// runs in background not to block the redraws on large buffers, and the
+// previous differences are used until it finishes.
+func (m *Manager) updateDiff() {
+	i, j, ok := m.diffWindows()
+	if !ok {
+		m.diff = diffState{}
+		return
+	}
+	a, b := m.windows[i], m.windows[j]
+	a.mu.Lock()
+	aBuf, aTick := a.buf, a.buf.changedTick
+	a.mu.Unlock()
+	b.mu.Lock()
+	bBuf, bTick := b.buf, b.buf.changedTick
+	b.mu.Unlock()
+	if m.diff.a == aBuf && m.diff.b == bBuf && m.diff.aTick == aTick &&
+		m.diff.bTick == bTick && m.diff.align == m.options.diffAlign {
+		return
+	}
+	var hunks []diff.Hunk
+	if m.diff.a == aBuf && m.diff.b == bBuf {
+		hunks = m.diff.hunks
+	}
+	done := make(chan struct{})
+	m.diff = diffState{a: aBuf, b: bBuf, aTick: aTick, bTick: bTick,
+		align: m.options.diffAlign, hunks: hunks, done: done}
+	if aBuf == bBuf {
+		m.diff.hunks = nil
+		close(done)
+		return
+	}
+	a.mu.Lock()
+	aBuffer := aBuf.buffer.Clone()
+	a.mu.Unlock()
+	b.mu.Lock()
+	bBuffer := bBuf.buffer.Clone()
+	b.mu.Unlock()
+	go func(align bool) {
+		hunks, err := computeDiff(aBuffer, bBuffer, align)
+		m.mu.Lock()
+		if m.diff.done == done {
+			m.diff.hunks, m.diff.err = hunks, err
+		}
+		m.mu.Unlock()
+		close(done)
+		select {
+		case m.eventCh <- event.Event{Type: event.Redraw}:
+		case <-m.done:
+		}
+	}(m.diff.align)
+}
+
+// waitDiff waits for the differences of the current buffers. It is called
+// with m.mu locked, which is released while waiting for the computation.
+func (m *Manager) waitDiff() error {
+	for {
+		m.updateDiff()
+		done := m.diff.done
+		if done == nil {
+			return nil
+		}
+		select {
+		case <-done:
+			return m.diff.err
+		default:
+		}
+		m.mu.Unlock()
+		<-done
+		m.mu.Lock()
+	}
+}
+
+// computeDiff computes the differences of the two buffers. The alignment
+// with insertions and deletions is tried only for the small buffers,
+// falling back to the positional comparison.
+func computeDiff(a, b *buffer.Buffer, align bool) ([]diff.Hunk, error) {
+	alen, err := a.Len()
+	if err != nil {
+		return nil, err
+	}
+	blen, err := b.Len()
+	if err != nil {
+		return nil, err
+	}
+	if align && alen <= maxDiffAlignSize && blen <= maxDiffAlignSize {
+		abs, bbs := make([]byte, alen), make([]byte, blen)
+		if _, err := a.ReadAt(abs, 0); err != nil && err != io.EOF {
+			return nil, err
+		}
+		if _, err := b.ReadAt(bbs, 0); err != nil && err != io.EOF {
+			return nil, err
+		}
+		if hunks, ok := diff.Aligned(abs, bbs, maxDiffAlignCost); ok {
+			return hunks, nil
+		}
+	}
+	return diff.Positional(a, b, alen, blen)
+}
+
+// diffSide returns whether the window at the index is the first window in
+// diff mode, and the index of the other window.
+func (m *Manager) diffSide(index int) (bool, int, error) {
+	i, j, ok := m.diffWindows()
+	if !ok || index != i && index != j {
+		return false, 0, errors.New("not in diff mode")
+	}
+	if index == i {
+		return true, j, nil
+	}
+	return false, i, nil
+}
+
+func hunkRange(h diff.Hunk, first bool) (int64, int64) {
+	if first {
+		return h.AFrom, h.ATo
+	}
+	return h.BFrom, h.BTo
+}
+
+// setDiffIndices sets the differing regions in the visible area of the
+// windows in diff mode.
+func (m *Manager) setDiffIndices(states map[int]*state.WindowState) {
+	i, j, ok := m.diffWindows()
+	if !ok {
+		return
+	}
+	for _, index := range []int{i, j} {
+		s, first := states[index], index == i
+		for _, h := range m.diff.hunks {
+			from, to := hunkRange(h, first)
+			if from < to && from < s.Offset+int64(s.Size) && s.Offset < to {
+				s.DiffIndices = append(s.DiffIndices, from, to)
+			}
+		}
+	}
+}
+
+// jumpDiff moves the cursor to the start of the next or previous hunk.
+func (m *Manager) jumpDiff(count int64, forward bool) error {
+	m.mu.Lock()
+	if err := m.waitDiff(); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	first, _, err := m.diffSide(m.windowIndex)
+	if err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	window := m.windows[m.windowIndex]
+	window.mu.Lock()
+	cursor := window.cursor
+	window.mu.Unlock()
+	var starts []int64
+	for _, h := range m.diff.hunks {
+		from, _ := hunkRange(h, first)
+		starts = append(starts, from)
+	}
+	m.mu.Unlock()
+	i := sort.Search(len(starts), func(i int) bool { return starts[i] > cursor })
+	count = mathutil.MaxInt64(count, 1)
+	if forward {
+		if i == len(starts) {
+			return nil
+		}
+		i = int(mathutil.MinInt64(int64(i)+count, int64(len(starts)))) - 1
+	} else {
+		for i > 0 && starts[i-1] >= cursor {
+			i--
+		}
+		if i == 0 {
+			return nil
+		}
+		i = int(mathutil.MaxInt64(int64(i)-count, 0))
+	}
+	window.eventCh <- event.Event{
+		Type:  event.CursorGoto,
+		Range: &event.Range{From: event.Absolute{Offset: starts[i]}},
+	}
+	return nil
+}
+
+// diffCopy copies the hunks at the cursor or in the range from the other
+// window in diff mode (:diffget), or to the other window (:diffput).
+func (m *Manager) diffCopy(e event.Event, get bool) error {
+	if len(e.Arg) > 0 {
+		return fmt.Errorf("too many arguments for %s", e.CmdName)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.waitDiff(); err != nil {
+		return err
+	}
+	first, other, err := m.diffSide(m.windowIndex)
+	if err != nil {
+		return err
+	}
+	window := m.windows[m.windowIndex]
+	window.mu.Lock()
+	from, to := window.cursor, window.cursor
+	if e.Range != nil {
+		if from, err = window.positionToOffset(e.Range.From); err == nil && e.Range.To != nil {
+			to, err = window.positionToOffset(e.Range.To)
+		} else {
+			to = from
+		}
+		if from > to {
+			from, to = to, from
+		}
+	}
+	window.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	var hunks []diff.Hunk
+	for _, h := range m.diff.hunks {
+		if f, t := hunkRange(h, first); f <= to && (from < t || f == from) {
+			hunks = append(hunks, h)
+		}
+	}
+	if len(hunks) == 0 {
+		return errors.New("no differences found")
+	}
+	src, dst, srcFirst := m.windows[other], window, !first
+	if !get {
+		src, dst, srcFirst = dst, src, first
+	}
+	dst.mu.Lock()
+	err = dst.buf.checkModifiable()
+	dst.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	// replace from the last hunk to keep the offsets of the preceding hunks
+	for k := len(hunks) - 1; k >= 0; k-- {
+		srcFrom, srcTo := hunkRange(hunks[k], srcFirst)
+		dstFrom, dstTo := hunkRange(hunks[k], !srcFirst)
+		src.mu.Lock()
+		_, bs, err := src.readBytes(srcFrom, int(srcTo-srcFrom))
+		src.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		dst.mu.Lock()
+		dst.replaceRange(dstFrom, dstTo, bs)
+		dst.mu.Unlock()
+	}
+	dst.mu.Lock()
+	dst.buf.push(dst.offset, dst.cursor)
+	dst.mu.Unlock()
+	return nil
+}
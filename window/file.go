@@ -0,0 +1,84 @@
+package window
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// fileReader reads the file as it was opened. The original bytes are kept
+// when the file is overwritten in place, so that the buffer and the undo
+// history reading the file keep their contents.
+type fileReader struct {
+	*os.File
+	patches []patch
+	tmp     string
+}
+
+type patch struct {
+	offset int64
+	bs     []byte
+}
+
+func newFileReader(f *os.File) *fileReader {
+	return &fileReader{File: f}
+}
+
+// ReadAt reads the original bytes of the file.
+func (r *fileReader) ReadAt(p []byte, offset int64) (int, error) {
+	n, err := r.File.ReadAt(p, offset)
+	for _, pt := range r.patches {
+		if from, to := pt.offset, pt.offset+int64(len(pt.bs)); from < offset+int64(n) && offset < to {
+			if from < offset {
+				copy(p[:n], pt.bs[offset-from:])
+			} else {
+				copy(p[from-offset:n], pt.bs)
+			}
+		}
+	}
+	return n, err
+}
+
+// keep saves the original bytes in the regions, which are going to be
+// overwritten. The indices are the pairs of the start and end offsets.
+func (r *fileReader) keep(eis []int64) error {
+	for i := 0; i < len(eis); i += 2 {
+		bs := make([]byte, eis[i+1]-eis[i])
+		n, err := r.ReadAt(bs, eis[i])
+		if err != nil && err != io.EOF {
+			return err
+		}
+		r.patches = append(r.patches, patch{eis[i], bs[:n]})
+	}
+	return nil
+}
+
+// detach copies the original contents to a temporary file and reads it
+// instead, which is used before overwriting the whole file.
+func (r *fileReader) detach() error {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile("", "bed-")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, io.NewSectionReader(r, 0, size)); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	r.Close()
+	r.File, r.patches, r.tmp = f, nil, f.Name()
+	return nil
+}
+
+// Close closes the file, and removes the temporary file.
+func (r *fileReader) Close() error {
+	err := r.File.Close()
+	if r.tmp != "" {
+		os.Remove(r.tmp)
+	}
+	return err
+}
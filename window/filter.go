@@ -0,0 +1,111 @@
+package window
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/mathutil"
+	"github.com/itchyny/bed/shell"
+)
+
+// filter replaces the bytes in the range with the output of the command
+// given the bytes as the input (:'<,'>!cmd). The replacement is undone at once.
+func (m *Manager) filter(e event.Event) error {
+	if e.Range == nil {
+		return fmt.Errorf("range required for %s", e.CmdName)
+	}
+	if e.Arg == "" {
+		return fmt.Errorf("an argument is required for %s", e.CmdName)
+	}
+	m.mu.Lock()
+	window := m.windows[m.windowIndex]
+	m.mu.Unlock()
+	window.mu.Lock()
+	from, to, err := window.rangeToOffsets(e.Range)
+	if err == nil {
+		err = window.buf.checkModifiable()
+	}
+	var input []byte
+	if err == nil {
+		var n int
+		n, input, err = window.readBytes(from, int(to-from+1))
+		input = input[:n]
+	}
+	window.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	output, err := shell.Filter(e.Arg, input)
+	if err != nil {
+		return err
+	}
+	window.mu.Lock()
+	defer window.mu.Unlock()
+	window.replaceRange(from, from+int64(len(input)), output)
+	window.buf.push(window.offset, window.cursor)
+	return nil
+}
+
+// read inserts the contents of the file (:r file) or the output of the
+// command (:r !cmd) at the cursor, or after the position (:[pos]r file).
+// As in Vim, :0r inserts at the head of the buffer.
+func (m *Manager) read(e event.Event) error {
+	if e.Arg == "" {
+		return errors.New("no file name")
+	}
+	m.mu.Lock()
+	window := m.windows[m.windowIndex]
+	m.mu.Unlock()
+	window.mu.Lock()
+	offset, err := window.readOffset(e.Range)
+	if err == nil {
+		err = window.buf.checkModifiable()
+	}
+	window.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	var bs []byte
+	if strings.HasPrefix(e.Arg, "!") {
+		if bs, err = shell.Filter(strings.TrimSpace(e.Arg[1:]), nil); err != nil {
+			return err
+		}
+	} else {
+		name, err := homedir.Expand(e.Arg)
+		if err != nil {
+			return err
+		}
+		if bs, err = ioutil.ReadFile(name); err != nil {
+			return err
+		}
+	}
+	window.mu.Lock()
+	window.sync()
+	offset = mathutil.MinInt64(offset, window.length)
+	window.replaceRange(offset, offset, bs)
+	window.buf.push(window.offset, window.cursor)
+	window.mu.Unlock()
+	m.eventCh <- event.Event{Type: event.Info, Error: fmt.Errorf("%s: %d (0x%x) bytes read", e.Arg, len(bs), len(bs))}
+	return nil
+}
+
+// readOffset returns the offset to insert the bytes read by :read.
+func (w *window) readOffset(r *event.Range) (int64, error) {
+	w.sync()
+	if r == nil {
+		return w.cursor, nil
+	}
+	if pos, ok := r.From.(event.Absolute); ok && pos.Offset == 0 && r.To == nil {
+		return 0, nil
+	}
+	_, to, err := w.rangeToOffsets(r)
+	if err != nil {
+		return 0, err
+	}
+	return mathutil.MinInt64(to+1, w.length), nil
+}
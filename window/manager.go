@@ -4,15 +4,22 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/mitchellh/go-homedir"
 
+	"github.com/itchyny/bed/buffer"
 	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/expr"
+	"github.com/itchyny/bed/history"
 	"github.com/itchyny/bed/layout"
 	"github.com/itchyny/bed/mathutil"
 	"github.com/itchyny/bed/state"
@@ -23,30 +30,46 @@ type Manager struct {
 	width           int
 	height          int
 	windows         []*window
+	buffers         []*fileBuffer
+	bufferID        int
 	layout          layout.Layout
 	mu              *sync.Mutex
 	windowIndex     int
 	prevWindowIndex int
+	tabPages        []tabPage
+	tabIndex        int
+	diff            diffState
+	readonly        bool
+	recoverFiles    map[string]string
+	stdin           io.Reader
+	stdinBuffer     *fileBuffer
 	files           []file
+	options         options
+	done            chan struct{}
+	watchStopped    chan struct{}
+	checkedBuffer   *fileBuffer
+	checkedTime     time.Time
 	eventCh         chan<- event.Event
 	redrawCh        chan<- struct{}
 }
 
 type file struct {
 	name string
-	file *os.File
+	file *fileReader
 	perm os.FileMode
 }
 
 // NewManager creates a new Manager.
 func NewManager() *Manager {
-	return &Manager{}
+	return &Manager{options: defaultOptions(), stdin: os.Stdin}
 }
 
 // Init initializes the Manager.
 func (m *Manager) Init(eventCh chan<- event.Event, redrawCh chan<- struct{}) {
 	m.eventCh, m.redrawCh = eventCh, redrawCh
 	m.mu = new(sync.Mutex)
+	m.done, m.watchStopped = make(chan struct{}), make(chan struct{})
+	go m.watch()
 }
 
 // Open a new window.
@@ -60,47 +83,136 @@ func (m *Manager) Open(filename string) error {
 	go window.run()
 	m.windows = append(m.windows, window)
 	m.windowIndex, m.prevWindowIndex = len(m.windows)-1, m.windowIndex
-	m.layout = layout.NewLayout(m.windowIndex).Resize(0, 0, m.width, m.height)
+	if len(m.tabPages) == 0 {
+		m.tabPages = append(m.tabPages, tabPage{})
+	}
+	m.layout = m.fitLayout(layout.NewLayout(m.windowIndex))
 	return nil
 }
 
+// Load the file into the buffer list without opening a window.
+func (m *Manager) Load(filename string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := m.openBuffer(filename)
+	return err
+}
+
+// Split opens a new window above or on the left of the current window.
+func (m *Manager) Split(filename string, vertical bool) error {
+	return m.newWindow(event.Event{Arg: filename}, vertical)
+}
+
 func (m *Manager) open(filename string) (*window, error) {
+	b, err := m.openBuffer(filename)
+	if err != nil {
+		return nil, err
+	}
+	return newBufferWindow(b, m.redrawCh)
+}
+
+// openBuffer returns the buffer of the file, reusing the buffer
+// if the file is already opened.
+func (m *Manager) openBuffer(filename string) (*fileBuffer, error) {
 	if filename == "" {
-		window, err := newWindow(bytes.NewReader(nil), "", "", m.redrawCh)
-		if err != nil {
-			return nil, err
-		}
-		return window, nil
+		return m.addBuffer(bytes.NewReader(nil), "", "")
+	}
+	if filename == "-" {
+		return m.openStdin()
 	}
 	name, err := homedir.Expand(filename)
 	if err != nil {
 		return nil, err
 	}
-	filename = name
+	if filename, err = filepath.Abs(name); err != nil {
+		return nil, err
+	}
+	for _, b := range m.buffers {
+		if b.filename == filename {
+			return b, nil
+		}
+	}
 	f, err := os.Open(filename)
 	if err != nil {
 		if !os.IsNotExist(err) {
 			return nil, err
 		}
-		window, err := newWindow(bytes.NewReader(nil), filename, filepath.Base(filename), m.redrawCh)
+		b, err := m.addBuffer(bytes.NewReader(nil), filename, filepath.Base(filename))
 		if err != nil {
 			return nil, err
 		}
-		return window, nil
+		b.options.readonly = m.readonly
+		if err := m.setSwap(b); err != nil {
+			m.unloadBuffer(b)
+			return nil, err
+		}
+		return b, nil
 	}
 	info, err := os.Stat(filename)
 	if err != nil {
+		f.Close()
 		return nil, err
 	}
 	if info.IsDir() {
+		f.Close()
 		return nil, fmt.Errorf("%s is a directory", filename)
 	}
-	m.files = append(m.files, file{name: filename, file: f, perm: info.Mode().Perm()})
-	window, err := newWindow(f, filename, filepath.Base(filename), m.redrawCh)
+	r := newFileReader(f)
+	m.files = append(m.files, file{name: filename, file: r, perm: info.Mode().Perm()})
+	b, err := m.addBuffer(r, filename, filepath.Base(filename))
+	if err != nil {
+		m.files = m.files[:len(m.files)-1]
+		r.Close()
+		return nil, err
+	}
+	b.options.readonly = m.readonly || info.Mode().IsRegular() && !isWritable(filename)
+	b.updateStat()
+	if err := m.setSwap(b); err != nil {
+		m.unloadBuffer(b)
+		return nil, err
+	}
+	return b, nil
+}
+
+// openStdin reads the standard input into the memory. The buffer has no file
+// name so that it is written to the file given to :write.
+func (m *Manager) openStdin() (*fileBuffer, error) {
+	if m.stdinBuffer != nil && m.bufferIndex(m.stdinBuffer) >= 0 {
+		return m.stdinBuffer, nil
+	}
+	bs, err := ioutil.ReadAll(m.stdin)
 	if err != nil {
 		return nil, err
 	}
-	return window, nil
+	if m.stdinBuffer, err = m.addBuffer(bytes.NewReader(bs), "", ""); err != nil {
+		return nil, err
+	}
+	return m.stdinBuffer, nil
+}
+
+func isWritable(filename string) bool {
+	f, err := os.OpenFile(filename, os.O_WRONLY, 0)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+func (m *Manager) addBuffer(r readAtSeeker, filename string, name string) (*fileBuffer, error) {
+	b, err := newFileBuffer(r, filename, name)
+	if err != nil {
+		return nil, err
+	}
+	m.bufferID++
+	b.id = m.bufferID
+	m.buffers = append(m.buffers, b)
+	return b, nil
+}
+
+// SetReadOnly sets whether the files are opened in read-only mode.
+func (m *Manager) SetReadOnly(readonly bool) {
+	m.readonly = readonly
 }
 
 // SetSize sets the size of the screen.
@@ -114,14 +226,14 @@ func (m *Manager) Resize(width, height int) {
 		m.mu.Lock()
 		defer m.mu.Unlock()
 		m.width, m.height = width, height
-		m.layout = m.layout.Resize(0, 0, width, height)
+		m.layout = m.fitLayout(m.layout)
 	}
 }
 
 // Emit an event to the current window.
 func (m *Manager) Emit(e event.Event) {
 	switch e.Type {
-	case event.Edit:
+	case event.Edit, event.View:
 		if err := m.edit(e); err != nil {
 			m.eventCh <- event.Event{Type: event.Error, Error: err}
 		} else {
@@ -139,6 +251,30 @@ func (m *Manager) Emit(e event.Event) {
 		} else {
 			m.eventCh <- event.Event{Type: event.Redraw}
 		}
+	case event.Split:
+		if err := m.split(e, false); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.Vsplit:
+		if err := m.split(e, true); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.Close:
+		if err := m.close(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.Only:
+		if err := m.only(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
 	case event.Wincmd:
 		if len(e.Arg) == 0 {
 			m.eventCh <- event.Event{Type: event.Error, Error: fmt.Errorf("an argument is required for %s", e.CmdName)}
@@ -213,6 +349,178 @@ func (m *Manager) Emit(e event.Event) {
 		} else {
 			m.eventCh <- event.Event{Type: event.Redraw}
 		}
+	case event.ExchangeWindow:
+		if err := m.exchange(e.Count); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.RotateWindowsDownwards:
+		if err := m.rotate(true, e.Count); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.RotateWindowsUpwards:
+		if err := m.rotate(false, e.Count); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.IncreaseWindowHeight:
+		if err := m.resize("+", e.Count); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.DecreaseWindowHeight:
+		if err := m.resize("-", e.Count); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.IncreaseWindowWidth:
+		if err := m.resize(">", e.Count); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.DecreaseWindowWidth:
+		if err := m.resize("<", e.Count); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.EqualizeWindows:
+		if err := m.resize("=", e.Count); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.SetWindowHeight:
+		if err := m.resize("_", e.Count); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.SetWindowWidth:
+		if err := m.resize("|", e.Count); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.Resize:
+		if err := m.resizeCommand(e, false); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.VerticalResize:
+		if err := m.resizeCommand(e, true); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.TabNew:
+		if err := m.tabNew(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.TabClose:
+		if err := m.tabClose(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.TabNext:
+		if err := m.tabNext(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.TabPrev:
+		if err := m.tabPrev(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.Marks:
+		if err := m.marks(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.Set:
+		if err := m.set(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.Echo:
+		if err := m.echo(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.Execute:
+		if err := m.execute(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.Buffers:
+		if err := m.listBuffers(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.Buffer:
+		if err := m.buffer(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.BufferNext:
+		if err := m.bufferNext(e, 1); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.BufferPrev:
+		if err := m.bufferNext(e, -1); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.BufferDelete:
+		if err := m.bufferDelete(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.DiffThis:
+		if err := m.diffThis(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.DiffOff:
+		if err := m.diffOff(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.DiffGet:
+		if err := m.diffCopy(e, true); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.DiffPut:
+		if err := m.diffCopy(e, false); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.NextDiff:
+		if err := m.jumpDiff(e.Count, true); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.PrevDiff:
+		if err := m.jumpDiff(e.Count, false); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
 	case event.Quit:
 		if err := m.quit(e); err != nil {
 			m.eventCh <- event.Event{Type: event.Error, Error: err}
@@ -225,26 +533,79 @@ func (m *Manager) Emit(e event.Event) {
 		if err := m.writeQuit(e); err != nil {
 			m.eventCh <- event.Event{Type: event.Error, Error: err}
 		}
+	case event.Read:
+		if err := m.read(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.Filter:
+		if err := m.filter(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.JumpToPointer:
+		if err := m.jumpToPointer(); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
 	default:
-		m.windows[m.windowIndex].eventCh <- e
+		window := m.windows[m.windowIndex]
+		if err := window.checkEdit(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			window.eventCh <- e
+		}
 	}
 }
 
 func (m *Manager) edit(e event.Event) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	var name string
-	if len(e.Arg) == 0 {
-		name = m.windows[m.windowIndex].filename
+	b := m.windows[m.windowIndex].buf
+	same := len(e.Arg) == 0
+	if !same {
+		if name, err := homedir.Expand(e.Arg); err == nil {
+			if filename, err := filepath.Abs(name); err == nil {
+				same = filename == b.filename
+			}
+		}
+	}
+	if same && b.filename == "" {
+		return errors.New("no file name")
+	}
+	if !same {
+		if err := m.checkAbandon(e.Bang, m.windowIndex); err != nil {
+			return err
+		}
+	} else if !e.Bang && b.modified() {
+		return errors.New("no write since last change (add ! to override)")
+	}
+	// read the file again, and discard the changes of the abandoned buffer with :e!
+	if same || b.modified() && !m.viewed(b, map[int]bool{m.windowIndex: true}) {
+		if err := m.reloadBuffer(b); err != nil {
+			return err
+		}
+	}
+	var window *window
+	var err error
+	if same {
+		window, err = newBufferWindow(b, m.redrawCh)
 	} else {
-		name = e.Arg
+		window, err = m.open(e.Arg)
 	}
-	window, err := m.open(name)
 	if err != nil {
 		return err
 	}
+	window.options = m.windows[m.windowIndex].options
+	if e.Type == event.View {
+		window.mu.Lock()
+		window.buf.options.readonly = true
+		window.mu.Unlock()
+	}
 	go window.run()
 	m.windows = append(m.windows, window)
+	m.removeWindow(m.windowIndex)
 	m.windowIndex, m.prevWindowIndex = len(m.windows)-1, m.windowIndex
 	m.layout = m.layout.Replace(m.windowIndex)
 	return nil
@@ -257,13 +618,36 @@ func (m *Manager) newWindow(e event.Event, vertical bool) error {
 	if err != nil {
 		return err
 	}
+	window.options = m.windows[m.windowIndex].options
 	go window.run()
 	m.windows = append(m.windows, window)
 	m.windowIndex, m.prevWindowIndex = len(m.windows)-1, m.windowIndex
 	if vertical {
-		m.layout = m.layout.SplitLeft(m.windowIndex).Resize(0, 0, m.width, m.height)
+		m.layout = m.fitLayout(m.layout.SplitLeft(m.windowIndex))
 	} else {
-		m.layout = m.layout.SplitTop(m.windowIndex).Resize(0, 0, m.width, m.height)
+		m.layout = m.fitLayout(m.layout.SplitTop(m.windowIndex))
+	}
+	return nil
+}
+
+// split the current window onto the same buffer at the same position.
+func (m *Manager) split(e event.Event, vertical bool) error {
+	if len(e.Arg) > 0 {
+		return m.newWindow(e, vertical)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	window, err := m.windows[m.windowIndex].split()
+	if err != nil {
+		return err
+	}
+	go window.run()
+	m.windows = append(m.windows, window)
+	m.windowIndex, m.prevWindowIndex = len(m.windows)-1, m.windowIndex
+	if vertical {
+		m.layout = m.fitLayout(m.layout.SplitLeft(m.windowIndex))
+	} else {
+		m.layout = m.fitLayout(m.layout.SplitTop(m.windowIndex))
 	}
 	return nil
 }
@@ -272,6 +656,24 @@ func (m *Manager) wincmd(arg string) error {
 	switch arg {
 	case "n":
 		return m.newWindow(event.Event{}, false)
+	case "s", "S":
+		return m.split(event.Event{}, false)
+	case "v":
+		return m.split(event.Event{}, true)
+	case "c":
+		return m.close(event.Event{})
+	case "o":
+		return m.only(event.Event{})
+	case "q":
+		return m.quit(event.Event{})
+	case "+", "-", "<", ">", "=", "_", "|":
+		return m.resize(arg, 0)
+	case "x":
+		return m.exchange(0)
+	case "r":
+		return m.rotate(true, 0)
+	case "R":
+		return m.rotate(false, 0)
 	case "l":
 		m.focus(func(x, y layout.Window) bool {
 			return x.LeftMargin()+x.Width()+1 == y.LeftMargin() &&
@@ -298,7 +700,8 @@ func (m *Manager) wincmd(arg string) error {
 		})
 	case "t":
 		m.focus(func(_, y layout.Window) bool {
-			return y.LeftMargin() == 0 && y.TopMargin() == 0
+			return m.layout.LeftMargin() == y.LeftMargin() &&
+				m.layout.TopMargin() == y.TopMargin()
 		})
 	case "b":
 		m.focus(func(_, y layout.Window) bool {
@@ -331,6 +734,96 @@ func (m *Manager) wincmd(arg string) error {
 	return nil
 }
 
+func (m *Manager) exchange(count int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.layout = m.fitLayout(m.layout.Exchange(int(count)))
+	if index := m.layout.ActiveWindow().Index; index != m.windowIndex {
+		m.windowIndex, m.prevWindowIndex = index, m.windowIndex
+	}
+	return nil
+}
+
+func (m *Manager) rotate(downwards bool, count int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := int64(0); i < mathutil.MaxInt64(count, 1); i++ {
+		m.layout = m.layout.Rotate(downwards)
+	}
+	m.layout = m.fitLayout(m.layout)
+	return nil
+}
+
+// resize the active window with the count for the wincmd.
+func (m *Manager) resize(arg string, count int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	active, n := m.layout.ActiveWindow(), int(mathutil.MaxInt64(count, 1))
+	switch arg {
+	case "+":
+		m.layout = m.layout.SetHeight(active.Height() + n)
+	case "-":
+		m.layout = m.layout.SetHeight(active.Height() - n)
+	case ">":
+		m.layout = m.layout.SetWidth(active.Width() + n)
+	case "<":
+		m.layout = m.layout.SetWidth(active.Width() - n)
+	case "=":
+		m.layout = m.layout.Equalize()
+	case "_":
+		if count == 0 {
+			count = int64(m.height)
+		}
+		m.layout = m.layout.SetHeight(int(count) + 2)
+	case "|":
+		if count == 0 {
+			count = int64(m.width)
+		}
+		m.layout = m.layout.SetWidth(int(count))
+	}
+	m.layout = m.fitLayout(m.layout)
+	return nil
+}
+
+func (m *Manager) resizeCommand(e event.Event, vertical bool) error {
+	if len(e.Arg) == 0 {
+		if vertical {
+			return m.resize("|", 0)
+		}
+		return m.resize("_", 0)
+	}
+	n, err := strconv.ParseInt(e.Arg, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid argument for %s: %s", e.CmdName, e.Arg)
+	}
+	switch {
+	case e.Arg[0] == '+' && vertical:
+		return m.resize(">", n)
+	case e.Arg[0] == '+':
+		return m.resize("+", n)
+	case e.Arg[0] == '-' && vertical:
+		return m.resize("<", -n)
+	case e.Arg[0] == '-':
+		return m.resize("-", -n)
+	case n <= 0:
+		return fmt.Errorf("invalid argument for %s: %s", e.CmdName, e.Arg)
+	case vertical:
+		return m.resize("|", n)
+	default:
+		return m.resize("_", n)
+	}
+}
+
+func (m *Manager) jumpToPointer() error {
+	m.mu.Lock()
+	window := m.windows[m.windowIndex]
+	m.mu.Unlock()
+	window.mu.Lock()
+	defer window.mu.Unlock()
+	window.sync()
+	return window.jumpToPointer()
+}
+
 func (m *Manager) focus(search func(layout.Window, layout.Window) bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -348,32 +841,427 @@ func (m *Manager) move(modifier func(layout.Window, layout.Layout) layout.Layout
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	activeWindow := m.layout.ActiveWindow()
-	m.layout = modifier(activeWindow, m.layout.Close()).Activate(
-		activeWindow.Index).Resize(0, 0, m.width, m.height)
+	m.layout = m.fitLayout(modifier(activeWindow, m.layout.Close()).Activate(
+		activeWindow.Index))
+}
+
+func (m *Manager) marks(e event.Event) error {
+	if len(e.Arg) > 0 {
+		return fmt.Errorf("too many arguments for %s", e.CmdName)
+	}
+	m.mu.Lock()
+	marks := m.windows[m.windowIndex].listMarks()
+	m.mu.Unlock()
+	if len(marks) == 0 {
+		return errors.New("no marks set")
+	}
+	m.eventCh <- event.Event{Type: event.Info, Error: errors.New(strings.Join(marks, ", "))}
+	return nil
+}
+
+func (m *Manager) echo(e event.Event) error {
+	if len(e.Arg) == 0 {
+		return fmt.Errorf("an argument is required for %s", e.CmdName)
+	}
+	m.mu.Lock()
+	v, err := m.windows[m.windowIndex].evaluate(e.Arg)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	m.eventCh <- event.Event{Type: event.Info, Error: errors.New(expr.Format(v))}
+	return nil
+}
+
+func (m *Manager) execute(e event.Event) error {
+	if len(e.Arg) == 0 {
+		return fmt.Errorf("an argument is required for %s", e.CmdName)
+	}
+	m.mu.Lock()
+	v, err := m.windows[m.windowIndex].evaluate(e.Arg)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	m.eventCh <- event.Event{Type: event.ExecuteCmdline, Arg: expr.String(v)}
+	return nil
+}
+
+func (m *Manager) listBuffers(e event.Event) error {
+	if len(e.Arg) > 0 {
+		return fmt.Errorf("too many arguments for %s", e.CmdName)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	active := make(map[*fileBuffer]bool)
+	for _, window := range m.windows {
+		if window != nil {
+			active[window.buf] = true
+		}
+	}
+	buffers := make([]string, len(m.buffers))
+	for i, b := range m.buffers {
+		flags := "h"
+		if b == m.windows[m.windowIndex].buf {
+			flags = "%a"
+		} else if active[b] {
+			flags = "a"
+		}
+		name := b.name
+		if name == "" {
+			name = "[No Name]"
+		}
+		buffers[i] = fmt.Sprintf("%d %s %q", b.id, flags, name)
+	}
+	m.eventCh <- event.Event{Type: event.Info, Error: errors.New(strings.Join(buffers, ", "))}
+	return nil
+}
+
+func (m *Manager) buffer(e event.Event) error {
+	if len(e.Arg) == 0 {
+		return fmt.Errorf("an argument is required for %s", e.CmdName)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.lookupBuffer(e.Arg)
+	if err != nil {
+		return err
+	}
+	return m.switchBuffer(b)
+}
+
+func (m *Manager) bufferNext(e event.Event, dir int) error {
+	if len(e.Arg) > 0 {
+		return fmt.Errorf("too many arguments for %s", e.CmdName)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.bufferIndex(m.windows[m.windowIndex].buf)
+	return m.switchBuffer(m.buffers[(i+dir+len(m.buffers))%len(m.buffers)])
+}
+
+func (m *Manager) bufferDelete(e event.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b := m.windows[m.windowIndex].buf
+	if len(e.Arg) > 0 {
+		var err error
+		if b, err = m.lookupBuffer(e.Arg); err != nil {
+			return err
+		}
+	}
+	if len(m.buffers) == 1 {
+		return errors.New("cannot delete the last buffer")
+	}
+	if !e.Bang && b.modified() {
+		return errors.New("no write since last change (add ! to override)")
+	}
+	alt := m.buffers[(m.bufferIndex(b)+1)%len(m.buffers)]
+	m.saveTabPage()
+	defer m.loadTabPage(m.tabIndex)
+	var removed []int
+	for k := range m.tabPages {
+		t := &m.tabPages[k]
+		for j := range t.layout.Collect() {
+			if m.windows[j].buf != b {
+				continue
+			}
+			window, err := m.bufferWindow(alt)
+			if err != nil {
+				return err
+			}
+			t.layout = t.layout.Activate(j).Replace(window)
+			removed = append(removed, j)
+			if j == t.windowIndex {
+				t.windowIndex = window
+			}
+		}
+		t.layout = t.layout.Activate(t.windowIndex)
+	}
+	for _, j := range removed {
+		m.removeWindow(j)
+	}
+	m.unloadBuffer(b)
+	return nil
+}
+
+func (m *Manager) lookupBuffer(arg string) (*fileBuffer, error) {
+	if id, err := strconv.Atoi(arg); err == nil {
+		for _, b := range m.buffers {
+			if b.id == id {
+				return b, nil
+			}
+		}
+		return nil, fmt.Errorf("buffer %d does not exist", id)
+	}
+	for _, b := range m.buffers {
+		if b.name == arg || b.filename != "" && b.filename == arg {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("no matching buffer for %s", arg)
+}
+
+func (m *Manager) bufferIndex(b *fileBuffer) int {
+	for i, c := range m.buffers {
+		if b == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// bufferWindow creates a new window viewing the buffer and returns its index.
+func (m *Manager) bufferWindow(b *fileBuffer) (int, error) {
+	window, err := newBufferWindow(b, m.redrawCh)
+	if err != nil {
+		return 0, err
+	}
+	window.options = m.windows[m.windowIndex].options
+	go window.run()
+	m.windows = append(m.windows, window)
+	return len(m.windows) - 1, nil
+}
+
+func (m *Manager) switchBuffer(b *fileBuffer) error {
+	if b == m.windows[m.windowIndex].buf {
+		return nil
+	}
+	window, err := m.bufferWindow(b)
+	if err != nil {
+		return err
+	}
+	m.removeWindow(m.windowIndex)
+	m.windowIndex, m.prevWindowIndex = window, m.windowIndex
+	m.layout = m.layout.Replace(m.windowIndex)
+	return nil
+}
+
+// removeWindow stops the window which is removed from the layout.
+func (m *Manager) removeWindow(index int) {
+	m.windows[index].close()
+	m.windows[index] = nil
+}
+
+// unloadBuffer removes the buffer from the buffer list and closes the file.
+func (m *Manager) unloadBuffer(b *fileBuffer) {
+	if i := m.bufferIndex(b); i >= 0 {
+		m.buffers = append(m.buffers[:i], m.buffers[i+1:]...)
+	}
+	m.closeFile(b.filename)
+	b.mu.Lock()
+	b.swap.remove()
+	b.mu.Unlock()
+}
+
+func (m *Manager) closeFile(name string) {
+	for i, f := range m.files {
+		if f.name == name {
+			f.file.Close()
+			m.files = append(m.files[:i], m.files[i+1:]...)
+			break
+		}
+	}
+}
+
+// closeWindow closes the active window, and unloads its buffer
+// if no other window views the buffer.
+func (m *Manager) closeWindow() {
+	index := m.windowIndex
+	m.layout = m.fitLayout(m.layout.Close())
+	m.windowIndex, m.prevWindowIndex = m.layout.ActiveWindow().Index, m.windowIndex
+	m.removeBufferWindow(index)
+}
+
+func (m *Manager) removeBufferWindow(index int) {
+	b := m.windows[index].buf
+	m.removeWindow(index)
+	for _, window := range m.windows {
+		if window != nil && window.buf == b {
+			return
+		}
+	}
+	m.unloadBuffer(b)
+}
+
+func (m *Manager) close(e event.Event) error {
+	if len(e.Arg) > 0 {
+		return fmt.Errorf("too many arguments for %s", e.CmdName)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if w, h := m.layout.Count(); w == 1 && h == 1 {
+		if len(m.tabPages) == 1 {
+			return errors.New("cannot close last window")
+		}
+		if err := m.checkAbandon(e.Bang, m.windowIndex); err != nil {
+			return err
+		}
+		m.closeTabPage()
+		return nil
+	}
+	if err := m.checkAbandon(e.Bang, m.windowIndex); err != nil {
+		return err
+	}
+	m.closeWindow()
+	return nil
+}
+
+func (m *Manager) only(e event.Event) error {
+	if len(e.Arg) > 0 {
+		return fmt.Errorf("too many arguments for %s", e.CmdName)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var indices []int
+	for i := range m.layout.Collect() {
+		if i != m.windowIndex {
+			indices = append(indices, i)
+		}
+	}
+	if err := m.checkAbandon(e.Bang, indices...); err != nil {
+		return err
+	}
+	for _, i := range indices {
+		m.removeBufferWindow(i)
+	}
+	m.layout = m.fitLayout(layout.NewLayout(m.windowIndex))
+	return nil
 }
 
 func (m *Manager) quit(e event.Event) error {
 	if len(e.Arg) > 0 {
 		return fmt.Errorf("too many arguments for %s", e.CmdName)
 	}
+	m.mu.Lock()
 	w, h := m.layout.Count()
-	if w == 1 && h == 1 {
-		m.eventCh <- event.Event{Type: event.QuitAll}
-	} else {
-		m.mu.Lock()
-		m.layout = m.layout.Close().Resize(0, 0, m.width, m.height)
-		m.windowIndex, m.prevWindowIndex = m.layout.ActiveWindow().Index, m.windowIndex
-		m.mu.Unlock()
-		m.eventCh <- event.Event{Type: event.Redraw}
+	tabPages := len(m.tabPages)
+	m.mu.Unlock()
+	if w == 1 && h == 1 && tabPages == 1 {
+		// the modified buffers are checked on quitting all the windows
+		m.eventCh <- event.Event{Type: event.QuitAll, Bang: e.Bang}
+		return nil
+	}
+	m.mu.Lock()
+	err := m.checkAbandon(e.Bang, m.windowIndex)
+	if err == nil {
+		if w == 1 && h == 1 {
+			m.closeTabPage()
+		} else {
+			m.closeWindow()
+		}
+	}
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	m.eventCh <- event.Event{Type: event.Redraw}
+	return nil
+}
+
+// CheckModified returns an error if any buffer is changed after the last
+// write, checking the buffer in the active window first.
+func (m *Manager) CheckModified() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.windows[m.windowIndex].buf.modified() {
+		return errors.New("no write since last change (add ! to override)")
+	}
+	for _, b := range m.buffers {
+		if b.modified() {
+			name := b.name
+			if name == "" {
+				name = "[No Name]"
+			}
+			return fmt.Errorf("no write since last change for buffer %q (add ! to override)", name)
+		}
+	}
+	return nil
+}
+
+// checkAbandon returns an error if closing the windows abandons the changes
+// of a buffer which no other window views, unless forced.
+func (m *Manager) checkAbandon(force bool, indices ...int) error {
+	if force {
+		return nil
+	}
+	closing := make(map[int]bool, len(indices))
+	for _, i := range indices {
+		closing[i] = true
+	}
+	for _, i := range indices {
+		if b := m.windows[i].buf; b.modified() && !m.viewed(b, closing) {
+			return errors.New("no write since last change (add ! to override)")
+		}
+	}
+	return nil
+}
+
+// viewed reports whether any window other than the excluded ones views the buffer.
+func (m *Manager) viewed(b *fileBuffer, excluded map[int]bool) bool {
+	for i, window := range m.windows {
+		if window != nil && window.buf == b && !excluded[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// reloadBuffer reads the file of the buffer again, discarding the changes.
+func (m *Manager) reloadBuffer(b *fileBuffer) error {
+	var r readAtSeeker = bytes.NewReader(nil)
+	var f *fileReader
+	var perm os.FileMode
+	if b.filename != "" {
+		if g, err := os.Open(b.filename); err != nil {
+			if !os.IsNotExist(err) {
+				return err
+			}
+		} else {
+			info, err := g.Stat()
+			if err != nil {
+				g.Close()
+				return err
+			}
+			f, perm = newFileReader(g), info.Mode().Perm()
+			r = f
+		}
+	}
+	buf := buffer.NewBuffer(r)
+	if _, err := buf.Len(); err != nil {
+		if f != nil {
+			f.Close()
+		}
+		return err
 	}
+	m.closeFile(b.filename)
+	if f != nil {
+		m.files = append(m.files, file{name: b.filename, file: f, perm: perm})
+	}
+	b.updateStat()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.setBuffer(buf)
+	b.history = history.NewHistory()
+	b.history.Push(buf, 0, 0)
+	b.savedTick = b.changedTick
+	b.swap.remove()
 	return nil
 }
 
 func (m *Manager) write(e event.Event) error {
+	if strings.HasPrefix(e.Arg, ">>") {
+		filename, n, err := m.appendFile(e.Range, strings.TrimSpace(e.Arg[2:]), e.Bang)
+		if err != nil {
+			return err
+		}
+		m.eventCh <- event.Event{Type: event.Info, Error: fmt.Errorf("%s: %d (0x%x) bytes appended", filename, n, n)}
+		return nil
+	}
 	if e.Range != nil && e.Arg == "" {
 		return fmt.Errorf("cannot overwrite partially with %s", e.CmdName)
 	}
-	filename, n, err := m.writeFile(e.Range, e.Arg)
+	filename, n, err := m.writeFile(e.Range, e.Arg, e.Bang)
 	if err != nil {
 		return err
 	}
@@ -388,10 +1276,10 @@ func (m *Manager) writeQuit(e event.Event) error {
 	if e.Range != nil {
 		return fmt.Errorf("range not allowed for %s", e.CmdName)
 	}
-	if _, _, err := m.writeFile(nil, ""); err != nil {
+	if _, _, err := m.writeFile(nil, "", e.Bang); err != nil {
 		return err
 	}
-	m.eventCh <- event.Event{Type: event.Quit}
+	m.eventCh <- event.Event{Type: event.Quit, Bang: e.Bang}
 	return nil
 }
 
@@ -399,6 +1287,12 @@ func (m *Manager) writeQuit(e event.Event) error {
 func (m *Manager) State() (map[int]*state.WindowState, layout.Layout, int, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.updateDiff()
+	if err := m.diff.err; err != nil {
+		m.diff.err = nil
+		return nil, m.layout, 0, err
+	}
+	m.syncScrollBind()
 	layouts := m.layout.Collect()
 	states := make(map[int]*state.WindowState, len(m.windows))
 	for i, window := range m.windows {
@@ -410,6 +1304,7 @@ func (m *Manager) State() (map[int]*state.WindowState, layout.Layout, int, error
 			}
 		}
 	}
+	m.setDiffIndices(states)
 	return states, m.layout, m.windowIndex, nil
 }
 
@@ -428,10 +1323,18 @@ func hexWindowWidth(width int) int {
 	return 4
 }
 
-func (m *Manager) writeFile(r *event.Range, name string) (string, int64, error) {
+// WriteTo writes the buffer or the range of the active window to the writer.
+func (m *Manager) WriteTo(r *event.Range, w io.Writer) (int64, error) {
+	m.mu.Lock()
+	window := m.windows[m.windowIndex]
+	m.mu.Unlock()
+	return window.writeTo(r, w)
+}
+
+func (m *Manager) writeFile(r *event.Range, name string, force bool) (string, int64, error) {
 	window := m.windows[m.windowIndex]
 	if name == "" {
-		name = window.filename
+		name = window.buf.filename
 	}
 	if name == "" {
 		return name, 0, errors.New("no file name")
@@ -440,15 +1343,50 @@ func (m *Manager) writeFile(r *event.Range, name string) (string, int64, error)
 	if name, err = homedir.Expand(name); err != nil {
 		return name, 0, err
 	}
-	if window.filename == "" && window.name == "" {
-		window.filename = name
-		window.name = filepath.Base(name)
+	filename, err := filepath.Abs(name)
+	if err != nil {
+		return name, 0, err
+	}
+	if window.buf.options.readonly && !force && filename == window.buf.filename {
+		return name, 0, errors.New("'readonly' option is set (add ! to override)")
+	}
+	if !force && filename == window.buf.filename {
+		if _, changed := window.buf.changedOnDisk(); changed {
+			return name, 0, errors.New("file changed on disk since reading it (add ! to override)")
+		}
+	}
+	if window.buf.filename == "" && window.buf.name == "" {
+		window.buf.filename = filename
+		window.buf.name = filepath.Base(name)
 	}
+	if r == nil && filename == window.buf.filename && m.options.backupCopy != "no" {
+		n, ok, err := m.writeInPlace(window, m.options.backupCopy == "yes", force)
+		if err != nil {
+			return name, 0, err
+		}
+		if ok {
+			return name, n, nil
+		}
+	}
+	// write to the target file of the symbolic link to keep the link
+	path, perm := resolveSymlinks(name), m.filePerm(filename)
+	info, err := os.Stat(path)
+	if err == nil {
+		perm = info.Mode().Perm()
+	}
+	window.mu.Lock()
+	changedTick := window.buf.changedTick
+	window.mu.Unlock()
 	tmpf, err := os.OpenFile(
-		name+"-"+strconv.FormatUint(rand.Uint64(), 16),
-		os.O_RDWR|os.O_CREATE|os.O_EXCL, m.filePerm(name),
+		path+"-"+strconv.FormatUint(rand.Uint64(), 16),
+		os.O_RDWR|os.O_CREATE|os.O_EXCL, perm,
 	)
 	if err != nil {
+		if os.IsPermission(err) && info != nil {
+			// the directory is not writable, overwrite the existing file
+			n, err := m.writeExisting(window, r, path, filename, force)
+			return name, n, err
+		}
 		return name, 0, err
 	}
 	defer os.Remove(tmpf.Name())
@@ -457,7 +1395,62 @@ func (m *Manager) writeFile(r *event.Range, name string) (string, int64, error)
 	if err != nil {
 		return name, 0, err
 	}
-	return name, n, os.Rename(tmpf.Name(), name)
+	if info != nil {
+		copyAttrs(path, tmpf.Name())
+		if err := m.backupForRename(path, force); err != nil {
+			return name, 0, err
+		}
+	}
+	if err := os.Rename(tmpf.Name(), path); err != nil {
+		return name, 0, err
+	}
+	if r == nil && filename == window.buf.filename {
+		window.mu.Lock()
+		window.buf.savedTick = changedTick
+		window.buf.swap.remove()
+		window.mu.Unlock()
+		window.buf.updateStat()
+	}
+	return name, n, nil
+}
+
+// appendFile appends the buffer or the range to the file (:w >> file).
+// The file is created if it does not exist only when forced.
+func (m *Manager) appendFile(r *event.Range, name string, force bool) (string, int64, error) {
+	window := m.windows[m.windowIndex]
+	if name == "" {
+		name = window.buf.filename
+	}
+	if name == "" {
+		return name, 0, errors.New("no file name")
+	}
+	var err error
+	if name, err = homedir.Expand(name); err != nil {
+		return name, 0, err
+	}
+	filename, err := filepath.Abs(name)
+	if err != nil {
+		return name, 0, err
+	}
+	if window.buf.options.readonly && !force && filename == window.buf.filename {
+		return name, 0, errors.New("'readonly' option is set (add ! to override)")
+	}
+	flag := os.O_WRONLY | os.O_APPEND
+	if force {
+		flag |= os.O_CREATE
+	}
+	f, err := os.OpenFile(name, flag, m.filePerm(filename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return name, 0, fmt.Errorf("%s does not exist (add ! to create)", name)
+		}
+		return name, 0, err
+	}
+	n, err := window.writeTo(r, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return name, n, err
 }
 
 func (m *Manager) filePerm(name string) os.FileMode {
@@ -471,10 +1464,21 @@ func (m *Manager) filePerm(name string) os.FileMode {
 
 // Close the Manager.
 func (m *Manager) Close() {
+	if m.done != nil {
+		close(m.done)
+		<-m.watchStopped
+	}
 	for _, f := range m.files {
 		f.file.Close()
 	}
 	for _, w := range m.windows {
-		w.close()
+		if w != nil {
+			w.close()
+		}
+	}
+	for _, b := range m.buffers {
+		b.mu.Lock()
+		b.swap.remove()
+		b.mu.Unlock()
 	}
 }
@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/itchyny/bed/event"
 	"github.com/itchyny/bed/layout"
@@ -97,6 +98,26 @@ func TestManagerOpenStates(t *testing.T) {
 	wm.Close()
 }
 
+func TestManagerOpenDirectory(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	dir, err := ioutil.TempDir("", "bed-test-manager-open-directory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	expected := dir + " is a directory"
+	if err := wm.Open(dir); err == nil || err.Error() != expected {
+		t.Errorf("err should be %q but got: %v", expected, err)
+	}
+	if len(wm.files) != 0 {
+		t.Errorf("files should be empty but got: %v", wm.files)
+	}
+	wm.Close()
+}
+
 func TestManagerOpenNonExistsWrite(t *testing.T) {
 	wm := NewManager()
 	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
@@ -204,3 +225,1557 @@ func TestManagerWincmd(t *testing.T) {
 
 	wm.Close()
 }
+
+func TestManagerEcho(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	f, err := ioutil.TempFile("", "bed-test-manager-echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("MZ\x90\x00\x3c\x00\x00\x00"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := wm.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	go wm.Emit(event.Event{Type: event.Echo, CmdName: "echo", Arg: "u32le(4) + len()"})
+	e := <-eventCh
+	if e.Type != event.Info {
+		t.Errorf("event type should be %d but got: %d", event.Info, e.Type)
+	}
+	if expected := "0x44 68 0b1000100"; e.Error == nil || e.Error.Error() != expected {
+		t.Errorf("echo should report %q but got: %v", expected, e.Error)
+	}
+	go wm.Emit(event.Event{Type: event.Execute, CmdName: "execute", Arg: `"goto " . u32le(4)`})
+	e = <-eventCh
+	if e.Type != event.ExecuteCmdline {
+		t.Errorf("event type should be %d but got: %d", event.ExecuteCmdline, e.Type)
+	}
+	if expected := "goto 60"; e.Arg != expected {
+		t.Errorf("execute should emit %q but got: %q", expected, e.Arg)
+	}
+	go wm.Emit(event.Event{Type: event.Echo, CmdName: "echo", Arg: "u32le(6)"})
+	e = <-eventCh
+	if e.Type != event.Error {
+		t.Errorf("event type should be %d but got: %d", event.Error, e.Type)
+	}
+	wm.Close()
+}
+
+func TestManagerSet(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	if err := wm.Open(""); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	for _, testCase := range []struct {
+		arg      string
+		expected string
+		typ      event.Type
+	}{
+		{"", "jumpformat=u32le  jumpbase=0x0  noscrollbind  scrollalign=absolute  nodiff  nodiffalign  noreadonly  modifiable  backupcopy=no  nobackup  nowritebackup  backupdir=.  backupext=~  nobackupnumbered  nowatch", event.Info},
+		{"jf=u16be jumpbase=0x400000", "", event.Nop},
+		{"jumpformat? jb", "jumpformat=u16be  jumpbase=0x400000", event.Info},
+		{"jumpformat=u24le", "invalid value for jumpformat: u24le", event.Error},
+		{"jumpbase:foo", "invalid value for jumpbase: foo", event.Error},
+		{"nojumpbase", "unknown option: nojumpbase", event.Error},
+		{"scb sca=relative", "", event.Nop},
+		{"scrollbind? sca", "scrollbind  scrollalign=relative", event.Info},
+		{"invscb", "", event.Nop},
+		{"scb?", "noscrollbind", event.Info},
+		{"sca=center", "invalid value for scrollalign: center", event.Error},
+		{"scb=1", "invalid argument: scb=1", event.Error},
+		{"foo", "unknown option: foo", event.Error},
+	} {
+		if testCase.typ == event.Nop {
+			wm.Emit(event.Event{Type: event.Set, CmdName: "set", Arg: testCase.arg})
+			continue
+		}
+		go wm.Emit(event.Event{Type: event.Set, CmdName: "set", Arg: testCase.arg})
+		e := <-eventCh
+		if e.Type != testCase.typ {
+			t.Errorf("event type should be %d but got: %d", testCase.typ, e.Type)
+		}
+		if e.Error == nil || e.Error.Error() != testCase.expected {
+			t.Errorf("set %s should report %q but got: %v", testCase.arg, testCase.expected, e.Error)
+		}
+	}
+	wm.Close()
+}
+
+func TestManagerBuffers(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	f, err := ioutil.TempFile("", "bed-test-manager-buffers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("Hello, world!"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := wm.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	name := filepath.Base(f.Name())
+
+	emit := func(e event.Event) event.Event {
+		go wm.Emit(e)
+		return <-eventCh
+	}
+	if e := emit(event.Event{Type: event.Vnew, Arg: f.Name()}); e.Type != event.Redraw {
+		t.Errorf("event type should be %d but got: %d", event.Redraw, e.Type)
+	}
+	if len(wm.buffers) != 1 {
+		t.Errorf("buffers should have %d entries but got %d", 1, len(wm.buffers))
+	}
+	_, _, _, _ = wm.State()
+	wm.Emit(event.Event{Type: event.DeleteByte})
+	<-redrawCh
+	windowStates, _, _, _ := wm.State()
+	for i, ws := range windowStates {
+		if ws.Length != 12 {
+			t.Errorf("Length of window %d should be %d but got %d", i, 12, ws.Length)
+		}
+		if expected := "ello, world!"; !strings.HasPrefix(string(ws.Bytes), expected) {
+			t.Errorf("Bytes of window %d should start with %q but got %q", i, expected, string(ws.Bytes))
+		}
+	}
+
+	for _, testCase := range []struct {
+		event    event.Event
+		typ      event.Type
+		expected string
+	}{
+		{event.Event{Type: event.New}, event.Redraw, ""},
+		{event.Event{Type: event.Buffers}, event.Info, `1 a "` + name + `", 2 %a "[No Name]"`},
+		{event.Event{Type: event.BufferNext}, event.Redraw, ""},
+		{event.Event{Type: event.Buffers}, event.Info, `1 %a "` + name + `", 2 h "[No Name]"`},
+		{event.Event{Type: event.Buffer, Arg: "2"}, event.Redraw, ""},
+		{event.Event{Type: event.Buffers}, event.Info, `1 a "` + name + `", 2 %a "[No Name]"`},
+		{event.Event{Type: event.Buffer, Arg: "3"}, event.Error, "buffer 3 does not exist"},
+		{event.Event{Type: event.BufferPrev}, event.Redraw, ""},
+		{event.Event{Type: event.BufferDelete, Arg: "1"}, event.Error,
+			"no write since last change (add ! to override)"},
+		{event.Event{Type: event.BufferDelete, Arg: "1", Bang: true}, event.Redraw, ""},
+		{event.Event{Type: event.Buffers}, event.Info, `2 %a "[No Name]"`},
+		{event.Event{Type: event.BufferDelete}, event.Error, "cannot delete the last buffer"},
+	} {
+		e := emit(testCase.event)
+		if e.Type != testCase.typ {
+			t.Errorf("event type should be %d but got: %d", testCase.typ, e.Type)
+		}
+		if testCase.expected != "" && (e.Error == nil || e.Error.Error() != testCase.expected) {
+			t.Errorf("event should report %q but got: %v", testCase.expected, e.Error)
+		}
+	}
+	windowStates, _, _, _ = wm.State()
+	if len(windowStates) != 3 {
+		t.Errorf("windowStates should have %d entries but got %d", 3, len(windowStates))
+	}
+	for i, ws := range windowStates {
+		if ws.Name != "" {
+			t.Errorf("name of window %d should be %q but got %q", i, "", ws.Name)
+		}
+	}
+	wm.Close()
+}
+
+func TestManagerSplit(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	f, err := ioutil.TempFile("", "bed-test-manager-split")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(strings.Repeat("Hello, world!", 100)); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := wm.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+	wm.Emit(event.Event{Type: event.CursorDown, Count: 3})
+	<-redrawCh
+
+	go wm.Emit(event.Event{Type: event.Split})
+	if e := <-eventCh; e.Type != event.Redraw {
+		t.Errorf("event type should be %d but got: %d", event.Redraw, e.Type)
+	}
+	go wm.Emit(event.Event{Type: event.Wincmd, Arg: "v"})
+	if e := <-eventCh; e.Type != event.Redraw {
+		t.Errorf("event type should be %d but got: %d", event.Redraw, e.Type)
+	}
+	windowStates, got, windowIndex, _ := wm.State()
+	expected := layout.NewLayout(0).SplitTop(1).SplitLeft(2).Resize(0, 0, 110, 20)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("layout should be %#v but got %#v", expected, got)
+	}
+	if windowIndex != 2 {
+		t.Errorf("window index should be %d but got %d", 2, windowIndex)
+	}
+	if len(wm.buffers) != 1 {
+		t.Errorf("buffers should have %d entries but got %d", 1, len(wm.buffers))
+	}
+	for i, ws := range windowStates {
+		if ws.Cursor != 48 {
+			t.Errorf("cursor of window %d should be %d but got %d", i, 48, ws.Cursor)
+		}
+		if ws.Name != filepath.Base(f.Name()) {
+			t.Errorf("name of window %d should be %q but got %q", i, filepath.Base(f.Name()), ws.Name)
+		}
+	}
+
+	wm.Emit(event.Event{Type: event.DeleteByte})
+	<-redrawCh
+	windowStates, _, _, _ = wm.State()
+	for i, ws := range windowStates {
+		if ws.Length != 1299 {
+			t.Errorf("Length of window %d should be %d but got %d", i, 1299, ws.Length)
+		}
+	}
+	wm.Close()
+}
+
+func TestManagerCloseOnly(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	var names []string
+	for i := 0; i < 2; i++ {
+		f, err := ioutil.TempFile("", "bed-test-manager-close")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(f.Name())
+		if err := f.Close(); err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		names = append(names, f.Name())
+	}
+	if err := wm.Open(names[0]); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	emit := func(e event.Event) event.Event {
+		go wm.Emit(e)
+		return <-eventCh
+	}
+	emit(event.Event{Type: event.Split})
+	emit(event.Event{Type: event.Vnew, Arg: names[1]})
+	emit(event.Event{Type: event.Split})
+	if len(wm.buffers) != 2 || len(wm.files) != 2 {
+		t.Fatalf("buffers and files should have %d entries but got %d and %d", 2, len(wm.buffers), len(wm.files))
+	}
+	window, file := wm.windows[3], wm.files[1].file
+
+	if e := emit(event.Event{Type: event.Close}); e.Type != event.Redraw {
+		t.Errorf("event type should be %d but got: %d", event.Redraw, e.Type)
+	}
+	if _, ok := <-window.eventCh; ok {
+		t.Errorf("closed window should stop receiving events")
+	}
+	if len(wm.buffers) != 2 || len(wm.files) != 2 {
+		t.Errorf("buffers and files should have %d entries but got %d and %d", 2, len(wm.buffers), len(wm.files))
+	}
+	if e := emit(event.Event{Type: event.Wincmd, Arg: "c"}); e.Type != event.Redraw {
+		t.Errorf("event type should be %d but got: %d", event.Redraw, e.Type)
+	}
+	if len(wm.buffers) != 1 || len(wm.files) != 1 {
+		t.Errorf("buffers and files should have %d entries but got %d and %d", 1, len(wm.buffers), len(wm.files))
+	}
+	if _, err := file.Read(make([]byte, 1)); err == nil {
+		t.Errorf("file of the unloaded buffer should be closed")
+	}
+
+	if e := emit(event.Event{Type: event.Only}); e.Type != event.Redraw {
+		t.Errorf("event type should be %d but got: %d", event.Redraw, e.Type)
+	}
+	_, got, windowIndex, _ := wm.State()
+	if expected := layout.NewLayout(1).Resize(0, 0, 110, 20); !reflect.DeepEqual(got, expected) {
+		t.Errorf("layout should be %#v but got %#v", expected, got)
+	}
+	for i, window := range wm.windows {
+		if (window != nil) != (i == windowIndex) {
+			t.Errorf("window %d should be removed but got %v", i, window)
+		}
+	}
+	if len(wm.buffers) != 1 || len(wm.files) != 1 {
+		t.Errorf("buffers and files should have %d entries but got %d and %d", 1, len(wm.buffers), len(wm.files))
+	}
+
+	e := emit(event.Event{Type: event.Close})
+	if e.Type != event.Error {
+		t.Errorf("event type should be %d but got: %d", event.Error, e.Type)
+	}
+	if expected := "cannot close last window"; e.Error == nil || e.Error.Error() != expected {
+		t.Errorf("close should report %q but got: %v", expected, e.Error)
+	}
+	wm.Close()
+}
+
+func TestManagerResize(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	go func() {
+		for {
+			select {
+			case <-eventCh:
+			case <-redrawCh:
+			}
+		}
+	}()
+	wm.SetSize(110, 20)
+	if err := wm.Open(""); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	wm.Emit(event.Event{Type: event.Split})
+	wm.Emit(event.Event{Type: event.Vsplit})
+
+	for _, testCase := range []struct {
+		event         event.Event
+		width, height int
+	}{
+		{event.Event{}, 55, 10},
+		{event.Event{Type: event.IncreaseWindowHeight}, 55, 11},
+		{event.Event{Type: event.DecreaseWindowHeight, Count: 5}, 55, 6},
+		{event.Event{Type: event.IncreaseWindowWidth, Count: 10}, 65, 6},
+		{event.Event{Type: event.DecreaseWindowWidth}, 64, 6},
+		{event.Event{Type: event.SetWindowHeight}, 64, 19},
+		{event.Event{Type: event.SetWindowWidth, Count: 30}, 30, 19},
+		{event.Event{Type: event.EqualizeWindows}, 55, 10},
+		{event.Event{Type: event.Resize, Arg: "5"}, 55, 7},
+		{event.Event{Type: event.Resize, Arg: "+3"}, 55, 10},
+		{event.Event{Type: event.VerticalResize, Arg: "-15"}, 40, 10},
+		{event.Event{Type: event.Wincmd, Arg: "="}, 55, 10},
+		{event.Event{Type: event.VerticalResize}, 108, 10},
+	} {
+		if testCase.event.Type != event.Nop {
+			wm.Emit(testCase.event)
+		}
+		_, got, _, _ := wm.State()
+		active := got.ActiveWindow()
+		if active.Width() != testCase.width || active.Height() != testCase.height {
+			t.Errorf("window size should be %dx%d but got %dx%d after %+v", testCase.width, testCase.height,
+				active.Width(), active.Height(), testCase.event)
+		}
+	}
+	wm.Close()
+}
+
+func TestManagerExchangeRotate(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	go func() {
+		for {
+			select {
+			case <-eventCh:
+			case <-redrawCh:
+			}
+		}
+	}()
+	wm.SetSize(110, 20)
+	if err := wm.Open(""); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	wm.Emit(event.Event{Type: event.Vsplit})
+	wm.Emit(event.Event{Type: event.Vsplit})
+
+	wm.Emit(event.Event{Type: event.ExchangeWindow})
+	_, got, windowIndex, _ := wm.State()
+	expected := layout.Vertical{
+		Left:  layout.Vertical{Left: layout.Window{Index: 1, Active: true}, Right: layout.Window{Index: 2}},
+		Right: layout.Window{Index: 0},
+	}.Resize(0, 0, 110, 20)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("layout should be %#v but got %#v", expected, got)
+	}
+	if windowIndex != 1 {
+		t.Errorf("window index should be %d but got %d", 1, windowIndex)
+	}
+
+	wm.Emit(event.Event{Type: event.RotateWindowsUpwards, Count: 2})
+	_, got, windowIndex, _ = wm.State()
+	expected = layout.Vertical{
+		Left:  layout.Vertical{Left: layout.Window{Index: 0}, Right: layout.Window{Index: 1, Active: true}},
+		Right: layout.Window{Index: 2},
+	}.Resize(0, 0, 110, 20)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("layout should be %#v but got %#v", expected, got)
+	}
+	if windowIndex != 1 {
+		t.Errorf("window index should be %d but got %d", 1, windowIndex)
+	}
+
+	wm.Emit(event.Event{Type: event.Wincmd, Arg: "r"})
+	_, got, _, _ = wm.State()
+	expected = layout.Vertical{
+		Left:  layout.Vertical{Left: layout.Window{Index: 2}, Right: layout.Window{Index: 0}},
+		Right: layout.Window{Index: 1, Active: true},
+	}.Resize(0, 0, 110, 20)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("layout should be %#v but got %#v", expected, got)
+	}
+	wm.Close()
+}
+
+func TestManagerTabPages(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	if err := wm.Open(""); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	emit := func(e event.Event) event.Event {
+		go wm.Emit(e)
+		return <-eventCh
+	}
+	if names, index := wm.TabPages(); !reflect.DeepEqual(names, []string{"[No Name]"}) || index != 0 {
+		t.Errorf("tab pages should be %q and %d but got %q and %d", []string{"[No Name]"}, 0, names, index)
+	}
+	emit(event.Event{Type: event.TabNew})
+	emit(event.Event{Type: event.Vsplit})
+	_, got, windowIndex, _ := wm.State()
+	if expected := layout.NewLayout(1).SplitLeft(2).Resize(0, 1, 110, 19); !reflect.DeepEqual(got, expected) {
+		t.Errorf("layout should be %#v but got %#v", expected, got)
+	}
+	if windowIndex != 2 {
+		t.Errorf("window index should be %d but got %d", 2, windowIndex)
+	}
+	expected := []string{"[No Name]", "2 [No Name]"}
+	if names, index := wm.TabPages(); !reflect.DeepEqual(names, expected) || index != 1 {
+		t.Errorf("tab pages should be %q and %d but got %q and %d", expected, 1, names, index)
+	}
+
+	for _, testCase := range []struct {
+		event       event.Event
+		tabIndex    int
+		windowIndex int
+	}{
+		{event.Event{Type: event.TabNext}, 0, 0},
+		{event.Event{Type: event.TabNext}, 1, 2},
+		{event.Event{Type: event.TabPrev, Count: 3}, 0, 0},
+		{event.Event{Type: event.TabNext, Count: 2}, 1, 2},
+		{event.Event{Type: event.TabNext, Arg: "1"}, 0, 0},
+		{event.Event{Type: event.TabNew}, 1, 3},
+		{event.Event{Type: event.TabNext}, 2, 2},
+		{event.Event{Type: event.TabClose}, 1, 3},
+		{event.Event{Type: event.Quit}, 0, 0},
+	} {
+		if e := emit(testCase.event); e.Type != event.Redraw {
+			t.Errorf("event type should be %d but got: %d (%v)", event.Redraw, e.Type, e.Error)
+		}
+		_, _, windowIndex, _ := wm.State()
+		if _, index := wm.TabPages(); index != testCase.tabIndex {
+			t.Errorf("tab index should be %d but got %d after %+v", testCase.tabIndex, index, testCase.event)
+		}
+		if windowIndex != testCase.windowIndex {
+			t.Errorf("window index should be %d but got %d after %+v", testCase.windowIndex, windowIndex, testCase.event)
+		}
+	}
+	_, got, _, _ = wm.State()
+	if expected := layout.NewLayout(0).Resize(0, 0, 110, 20); !reflect.DeepEqual(got, expected) {
+		t.Errorf("layout should be %#v but got %#v", expected, got)
+	}
+	for i, window := range wm.windows {
+		if (window != nil) != (i == 0) {
+			t.Errorf("window %d should be removed but got %v", i, window)
+		}
+	}
+	e := emit(event.Event{Type: event.TabClose})
+	if expected := "cannot close last tab page"; e.Type != event.Error || e.Error.Error() != expected {
+		t.Errorf("tabclose should report %q but got: %v", expected, e.Error)
+	}
+	wm.Close()
+}
+
+func TestManagerScrollBind(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	go func() {
+		for range eventCh {
+		}
+	}()
+	wm.SetSize(300, 20)
+	f, err := ioutil.TempFile("", "bed-test-manager-scrollbind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(strings.Repeat("Hello, world!", 100)); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := wm.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	wm.Emit(event.Event{Type: event.Set, Arg: "scrollbind"})
+	wm.Emit(event.Event{Type: event.Vsplit})
+	wm.Emit(event.Event{Type: event.Vnew})
+	_, _, _, _ = wm.State()
+
+	position := func(i int) (int64, int64) {
+		window := wm.windows[i]
+		window.mu.Lock()
+		defer window.mu.Unlock()
+		return window.offset, window.cursor
+	}
+	for _, testCase := range []struct {
+		window  int
+		event   event.Event
+		offsets [3]int64
+		cursors [3]int64
+	}{
+		{1, event.Event{Type: event.CursorDown, Count: 20}, [3]int64{48, 48, 0}, [3]int64{320, 320, 0}},
+		{0, event.Event{Type: event.CursorNext, Count: 3}, [3]int64{48, 48, 0}, [3]int64{323, 323, 0}},
+		{1, event.Event{Type: event.Set, Arg: "scrollalign=relative"}, [3]int64{48, 48, 0}, [3]int64{323, 323, 0}},
+		{0, event.Event{Type: event.Set, Arg: "noscrollbind"}, [3]int64{48, 48, 0}, [3]int64{323, 323, 0}},
+		{0, event.Event{Type: event.CursorUp, Count: 10}, [3]int64{48, 48, 0}, [3]int64{163, 323, 0}},
+		{0, event.Event{Type: event.Set, Arg: "scrollbind"}, [3]int64{48, 48, 0}, [3]int64{163, 323, 0}},
+		{1, event.Event{Type: event.CursorDown, Count: 2}, [3]int64{80, 80, 0}, [3]int64{195, 355, 0}},
+		{0, event.Event{Type: event.PageTop}, [3]int64{0, 0, 0}, [3]int64{0, 160, 0}},
+	} {
+		wm.mu.Lock()
+		wm.windowIndex = testCase.window
+		wm.layout = wm.layout.Activate(testCase.window)
+		wm.mu.Unlock()
+		wm.Emit(testCase.event)
+		if testCase.event.Type != event.Set {
+			<-redrawCh
+		}
+		_, _, _, _ = wm.State()
+		for i := 0; i < 3; i++ {
+			offset, cursor := position(i)
+			if offset != testCase.offsets[i] || cursor != testCase.cursors[i] {
+				t.Errorf("window %d should be at offset %d and cursor %d but got %d and %d after %+v",
+					i, testCase.offsets[i], testCase.cursors[i], offset, cursor, testCase.event)
+			}
+		}
+	}
+	wm.Close()
+}
+
+func TestManagerDiff(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	go func() {
+		for range eventCh {
+		}
+	}()
+	wm.SetSize(300, 20)
+	contents := strings.Repeat("0123456789abcdef", 4)
+	var names []string
+	for _, str := range []string{contents, contents[:5] + "XYZ" + contents[8:40] + "x" + contents[41:] + "!!"} {
+		f, err := ioutil.TempFile("", "bed-test-manager-diff")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(f.Name())
+		if _, err := f.WriteString(str); err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if err := f.Close(); err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		names = append(names, f.Name())
+	}
+	if err := wm.Open(names[1]); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := wm.Split(names[0], true); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	wm.Diff()
+
+	diffIndices := func() [2][]int64 {
+		wm.mu.Lock()
+		if err := wm.waitDiff(); err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		wm.mu.Unlock()
+		windowStates, _, _, err := wm.State()
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		return [2][]int64{windowStates[1].DiffIndices, windowStates[0].DiffIndices}
+	}
+	cursor := func() int64 {
+		window := wm.windows[wm.windowIndex]
+		window.mu.Lock()
+		defer window.mu.Unlock()
+		return window.cursor
+	}
+	if got, expected := diffIndices(), [2][]int64{{5, 8, 40, 41}, {5, 8, 40, 41, 64, 66}}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("diff indices should be %v but got %v", expected, got)
+	}
+
+	for _, testCase := range []struct {
+		event  event.Event
+		cursor int64
+	}{
+		{event.Event{Type: event.NextDiff}, 5},
+		{event.Event{Type: event.NextDiff, Count: 2}, 63},
+		{event.Event{Type: event.PrevDiff}, 40},
+	} {
+		wm.Emit(testCase.event)
+		<-redrawCh
+		if got := cursor(); got != testCase.cursor {
+			t.Errorf("cursor should be %d but got %d after %+v", testCase.cursor, got, testCase.event)
+		}
+	}
+
+	wm.Emit(event.Event{Type: event.DiffGet})
+	if got, expected := diffIndices(), [2][]int64{{5, 8}, {5, 8, 64, 66}}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("diff indices should be %v but got %v", expected, got)
+	}
+
+	wm.Emit(event.Event{Type: event.DiffPut, Range: &event.Range{
+		From: event.Absolute{Offset: 0}, To: event.Absolute{Offset: 10},
+	}})
+	if got, expected := diffIndices(), [2][]int64{nil, {64, 66}}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("diff indices should be %v but got %v", expected, got)
+	}
+
+	wm.Emit(event.Event{Type: event.Set, Arg: "diffalign"})
+	if got, expected := diffIndices(), [2][]int64{nil, {64, 66}}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("diff indices should be %v but got %v", expected, got)
+	}
+
+	wm.Emit(event.Event{Type: event.DiffOff})
+	if got, expected := diffIndices(), [2][]int64{nil, nil}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("diff indices should be %v but got %v", expected, got)
+	}
+	wm.Close()
+}
+
+func TestManagerReadOnly(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetReadOnly(true)
+	wm.SetSize(110, 20)
+	f, err := ioutil.TempFile("", "bed-test-manager-readonly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("Hello, world!"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := wm.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	defer os.Remove(f.Name() + ".out")
+	windowStates, _, _, _ := wm.State()
+	if !windowStates[0].ReadOnly {
+		t.Errorf("window should be read-only")
+	}
+	for _, testCase := range []struct {
+		event    event.Event
+		typ      event.Type
+		expected string
+	}{
+		{event.Event{Type: event.DeleteByte}, event.Error, "cannot make changes, 'readonly' is set"},
+		{event.Event{Type: event.Rune, Rune: '0', Mode: mode.Insert}, event.Error, "cannot make changes, 'readonly' is set"},
+		{event.Event{Type: event.Write}, event.Error, "'readonly' option is set (add ! to override)"},
+		{event.Event{Type: event.Write, Arg: f.Name() + ".out"}, event.Info, ""},
+		{event.Event{Type: event.Set, Arg: "noro noma"}, event.Nop, ""},
+		{event.Event{Type: event.Increment}, event.Error, "cannot make changes, 'modifiable' is off"},
+		{event.Event{Type: event.Set, Arg: "ma"}, event.Nop, ""},
+		{event.Event{Type: event.DeleteByte}, event.Redraw, ""},
+		{event.Event{Type: event.Write}, event.Info, ""},
+		{event.Event{Type: event.View}, event.Redraw, ""},
+		{event.Event{Type: event.DeleteByte}, event.Error, "cannot make changes, 'readonly' is set"},
+		{event.Event{Type: event.Write, Bang: true}, event.Info, ""},
+	} {
+		if testCase.typ == event.Nop {
+			wm.Emit(testCase.event)
+			continue
+		}
+		if testCase.typ == event.Redraw && testCase.event.Type == event.DeleteByte {
+			wm.Emit(testCase.event)
+			<-redrawCh
+			continue
+		}
+		go wm.Emit(testCase.event)
+		e := <-eventCh
+		if e.Type != testCase.typ {
+			t.Errorf("event type should be %d but got: %d after %+v", testCase.typ, e.Type, testCase.event)
+		}
+		if testCase.expected != "" && (e.Error == nil || e.Error.Error() != testCase.expected) {
+			t.Errorf("error should be %q but got: %v", testCase.expected, e.Error)
+		}
+	}
+	bs, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if string(bs) != "ello, world!" {
+		t.Errorf("file contents should be %q but got %q", "ello, world!", string(bs))
+	}
+	wm.Close()
+}
+
+func TestManagerModified(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	f, err := ioutil.TempFile("", "bed-test-manager-modified")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("Hello, world!"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := wm.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	modified := func() bool {
+		windowStates, _, windowIndex, _ := wm.State()
+		return windowStates[windowIndex].Modified
+	}
+	if modified() {
+		t.Errorf("buffer should not be modified")
+	}
+	for _, testCase := range []struct {
+		event    event.Event
+		typ      event.Type
+		expected string
+		modified bool
+	}{
+		{event.Event{Type: event.DeleteByte}, event.Redraw, "", true},
+		{event.Event{Type: event.Edit, Arg: f.Name() + ".other"}, event.Error,
+			"no write since last change (add ! to override)", true},
+		{event.Event{Type: event.Edit}, event.Error,
+			"no write since last change (add ! to override)", true},
+		{event.Event{Type: event.Quit}, event.QuitAll, "", true},
+		{event.Event{Type: event.Edit, Bang: true}, event.Redraw, "", false},
+		{event.Event{Type: event.Increment}, event.Redraw, "", true},
+		{event.Event{Type: event.Write}, event.Info, "", false},
+		{event.Event{Type: event.Decrement}, event.Redraw, "", true},
+		{event.Event{Type: event.Vnew}, event.Redraw, "", false},
+		{event.Event{Type: event.FocusWindowRight}, event.Redraw, "", true},
+		{event.Event{Type: event.BufferDelete}, event.Error,
+			"no write since last change (add ! to override)", true},
+		{event.Event{Type: event.Quit}, event.Error,
+			"no write since last change (add ! to override)", true},
+		{event.Event{Type: event.BufferDelete, Bang: true}, event.Redraw, "", false},
+		{event.Event{Type: event.Quit, Bang: true}, event.Redraw, "", false},
+	} {
+		if testCase.event.Type == event.DeleteByte || testCase.event.Type == event.Increment ||
+			testCase.event.Type == event.Decrement {
+			wm.Emit(testCase.event)
+			<-redrawCh
+		} else {
+			go wm.Emit(testCase.event)
+			e := <-eventCh
+			if e.Type != testCase.typ {
+				t.Errorf("event type should be %d but got: %d after %+v", testCase.typ, e.Type, testCase.event)
+			}
+			if testCase.expected != "" && (e.Error == nil || e.Error.Error() != testCase.expected) {
+				t.Errorf("error should be %q but got: %v", testCase.expected, e.Error)
+			}
+		}
+		if got := modified(); got != testCase.modified {
+			t.Errorf("modified should be %v but got %v after %+v", testCase.modified, got, testCase.event)
+		}
+	}
+	if err := wm.CheckModified(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	bs, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if string(bs) != "Iello, world!" {
+		t.Errorf("file contents should be %q but got %q", "Iello, world!", string(bs))
+	}
+	wm.Close()
+}
+
+func TestManagerChangedOnDisk(t *testing.T) {
+	defer func(interval time.Duration) { watchInterval = interval }(watchInterval)
+	watchInterval = 10 * time.Millisecond
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	f, err := ioutil.TempFile("", "bed-test-manager-changed-on-disk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("Hello"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := wm.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+	if err := wm.CheckChanged(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := ioutil.WriteFile(f.Name(), []byte("Hello, world!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := wm.CheckChanged(); err != nil {
+		t.Errorf("err should be nil in the interval but got: %v", err)
+	}
+	time.Sleep(watchInterval)
+	expected := filepath.Base(f.Name()) + ": file changed on disk (:e! to reload, :w! to overwrite)"
+	if err := wm.CheckChanged(); err == nil || err.Error() != expected {
+		t.Errorf("err should be %q but got: %v", expected, err)
+	}
+	time.Sleep(watchInterval)
+	if err := wm.CheckChanged(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	for _, testCase := range []struct {
+		event    event.Event
+		typ      event.Type
+		expected string
+	}{
+		{event.Event{Type: event.Write}, event.Error,
+			"file changed on disk since reading it (add ! to override)"},
+		{event.Event{Type: event.Edit, Bang: true}, event.Redraw, ""},
+		{event.Event{Type: event.Write}, event.Info, ""},
+	} {
+		go wm.Emit(testCase.event)
+		e := <-eventCh
+		if e.Type != testCase.typ {
+			t.Errorf("event type should be %d but got: %d after %+v", testCase.typ, e.Type, testCase.event)
+		}
+		if testCase.expected != "" && (e.Error == nil || e.Error.Error() != testCase.expected) {
+			t.Errorf("error should be %q but got: %v", testCase.expected, e.Error)
+		}
+	}
+	windowStates, _, windowIndex, _ := wm.State()
+	if ws := windowStates[windowIndex]; string(ws.Bytes[:ws.Length]) != "Hello, world!" {
+		t.Errorf("buffer should be %q but got %q", "Hello, world!", string(ws.Bytes[:ws.Length]))
+	}
+	if err := wm.CheckChanged(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	wm.Emit(event.Event{Type: event.Set, Arg: "watch"})
+	if err := ioutil.WriteFile(f.Name(), []byte("Hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	<-redrawCh
+	if err := wm.CheckChanged(); err == nil || err.Error() != expected {
+		t.Errorf("err should be %q but got: %v", expected, err)
+	}
+	go wm.Emit(event.Event{Type: event.Write, Bang: true})
+	if e := <-eventCh; e.Type != event.Info {
+		t.Errorf("event type should be %d but got: %d (%v)", event.Info, e.Type, e.Error)
+	}
+	if err := wm.CheckChanged(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	bs, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if string(bs) != "Hello, world!" {
+		t.Errorf("file contents should be %q but got %q", "Hello, world!", string(bs))
+	}
+	wm.Close()
+}
+
+func TestManagerSwapFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "bed-test-manager-swap-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer os.Remove(SwapFileName(f.Name()))
+	if _, err := f.WriteString("Hello, world!"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	open := func(recover bool) (*Manager, chan event.Event, chan struct{}) {
+		wm := NewManager()
+		eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+		wm.Init(eventCh, redrawCh)
+		wm.SetSize(110, 20)
+		if recover {
+			if _, err := wm.Recover(f.Name()); err != nil {
+				t.Errorf("err should be nil but got: %v", err)
+			}
+		}
+		if err := wm.Open(f.Name()); err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		_, _, _, _ = wm.State()
+		return wm, eventCh, redrawCh
+	}
+	contents := func(wm *Manager) string {
+		windowStates, _, windowIndex, _ := wm.State()
+		ws := windowStates[windowIndex]
+		return string(ws.Bytes[:ws.Length])
+	}
+
+	wm1, _, redrawCh := open(false)
+	if _, err := os.Stat(SwapFileName(f.Name())); !os.IsNotExist(err) {
+		t.Errorf("swap file should not be created before changes but got: %v", err)
+	}
+	for _, e := range []event.Event{
+		{Type: event.DeleteByte}, {Type: event.Increment}, {Type: event.Increment},
+		{Type: event.Undo}, {Type: event.Redo}, {Type: event.DeleteByte},
+	} {
+		wm1.Emit(e)
+		<-redrawCh
+	}
+	if got, expected := contents(wm1), "llo, world!"; got != expected {
+		t.Errorf("buffer should be %q but got %q", expected, got)
+	}
+	if _, err := os.Stat(SwapFileName(f.Name())); err != nil {
+		t.Errorf("swap file should be created but got: %v", err)
+	}
+
+	// another session does not overwrite the swap file, but journals to the next one
+	swapName := SwapFileName(f.Name())
+	swapName = swapName[:len(swapName)-1] + "o"
+	defer os.Remove(swapName)
+	wm2, _, redrawCh := open(false)
+	expected := filepath.Base(f.Name()) + ": found a swap file, the changes are saved to " + filepath.Base(swapName) + " instead"
+	if err := wm2.CheckChanged(); err == nil || err.Error() != expected {
+		t.Errorf("err should be %q but got: %v", expected, err)
+	}
+	wm2.Emit(event.Event{Type: event.DeleteByte})
+	<-redrawCh
+	if _, err := os.Stat(swapName); err != nil {
+		t.Errorf("swap file should be created but got: %v", err)
+	}
+	wm4 := NewManager()
+	wm4.Init(make(chan event.Event), make(chan struct{}))
+	wm4.SetSize(110, 20)
+	if name, err := wm4.Recover(swapName); err != nil || name != f.Name() {
+		t.Errorf("name should be %q but got: %q (%v)", f.Name(), name, err)
+	}
+	if err := wm4.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if got, expected := contents(wm4), "ello, world!"; got != expected {
+		t.Errorf("buffer should be %q but got %q", expected, got)
+	}
+	wm4.Close()
+	wm2.Close()
+
+	wm3, eventCh, redrawCh := open(true)
+	if got, expected := contents(wm3), "llo, world!"; got != expected {
+		t.Errorf("buffer should be %q but got %q", expected, got)
+	}
+	if err := wm3.CheckModified(); err == nil {
+		t.Errorf("recovered buffer should be modified")
+	}
+	wm3.Emit(event.Event{Type: event.Undo})
+	<-redrawCh
+	if got, expected := contents(wm3), "gllo, world!"; got != expected {
+		t.Errorf("buffer should be %q but got %q", expected, got)
+	}
+	go wm3.Emit(event.Event{Type: event.Write})
+	if e := <-eventCh; e.Type != event.Info {
+		t.Errorf("event type should be %d but got: %d (%v)", event.Info, e.Type, e.Error)
+	}
+	if _, err := os.Stat(SwapFileName(f.Name())); !os.IsNotExist(err) {
+		t.Errorf("swap file should be removed after writing but got: %v", err)
+	}
+	wm3.Close()
+	bs, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if string(bs) != "gllo, world!" {
+		t.Errorf("file contents should be %q but got %q", "gllo, world!", string(bs))
+	}
+	wm1.Close()
+}
+
+func TestManagerSwapFileReplaceAppend(t *testing.T) {
+	f, err := ioutil.TempFile("", "bed-test-manager-swap-file-replace-append")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer os.Remove(SwapFileName(f.Name()))
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	wm1 := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm1.Init(eventCh, redrawCh)
+	wm1.SetSize(110, 20)
+	if err := wm1.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm1.State()
+	events := []event.Event{{Type: event.StartReplace}, {Type: event.SwitchFocus}}
+	for _, c := range "abc" {
+		events = append(events, event.Event{Type: event.Rune, Rune: c, Mode: mode.Replace})
+	}
+	for _, e := range append(events, event.Event{Type: event.ExitInsert}) {
+		wm1.Emit(e)
+		<-redrawCh
+	}
+	windowStates, _, windowIndex, _ := wm1.State()
+	if ws := windowStates[windowIndex]; string(ws.Bytes[:ws.Length]) != "abc" {
+		t.Errorf("buffer should be %q but got %q", "abc", string(ws.Bytes[:ws.Length]))
+	}
+
+	wm2 := NewManager()
+	wm2.Init(make(chan event.Event), make(chan struct{}))
+	wm2.SetSize(110, 20)
+	if _, err := wm2.Recover(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := wm2.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	windowStates, _, windowIndex, _ = wm2.State()
+	if ws := windowStates[windowIndex]; string(ws.Bytes[:ws.Length]) != "abc" {
+		t.Errorf("recovered buffer should be %q but got %q", "abc", string(ws.Bytes[:ws.Length]))
+	}
+	wm2.Close()
+	wm1.Close()
+}
+
+func TestManagerSwapFileFilter(t *testing.T) {
+	f, err := ioutil.TempFile("", "bed-test-manager-swap-file-filter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer os.Remove(SwapFileName(f.Name()))
+	if _, err := f.WriteString("Hello, world!"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	wm1 := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm1.Init(eventCh, redrawCh)
+	wm1.SetSize(110, 20)
+	if err := wm1.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm1.State()
+	for _, e := range []event.Event{
+		{Type: event.Filter, Range: &event.Range{
+			From: event.Absolute{Offset: 0}, To: event.Absolute{Offset: 4},
+		}, Arg: "tr a-z A-Z"},
+		{Type: event.Filter, Range: &event.Range{
+			From: event.Absolute{Offset: 5}, To: event.End{},
+		}, Arg: "tr -d o"},
+		{Type: event.Read, Range: &event.Range{From: event.Absolute{Offset: 6}}, Arg: "!printf 'big '"},
+	} {
+		go wm1.Emit(e)
+		if e := <-eventCh; e.Type == event.Error {
+			t.Errorf("err should be nil but got: %v", e.Error)
+		}
+	}
+	expected := "HELLO, big wrld!"
+	windowStates, _, windowIndex, _ := wm1.State()
+	if ws := windowStates[windowIndex]; string(ws.Bytes[:ws.Length]) != expected {
+		t.Errorf("buffer should be %q but got %q", expected, string(ws.Bytes[:ws.Length]))
+	}
+
+	wm2 := NewManager()
+	wm2.Init(make(chan event.Event), make(chan struct{}))
+	wm2.SetSize(110, 20)
+	if _, err := wm2.Recover(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := wm2.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	windowStates, _, windowIndex, _ = wm2.State()
+	if ws := windowStates[windowIndex]; string(ws.Bytes[:ws.Length]) != expected {
+		t.Errorf("recovered buffer should be %q but got %q", expected, string(ws.Bytes[:ws.Length]))
+	}
+	wm2.Close()
+	wm1.Close()
+}
+
+func TestManagerJumpToPointer(t *testing.T) {
+	wm := NewManager()
+	wm.stdin = strings.NewReader("\x08\x00\x00\x00" + strings.Repeat("\x00", 4) + "\x20\x00")
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	if err := wm.Open("-"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+	for _, testCase := range []struct {
+		typ      event.Type
+		cursor   int64
+		expected string
+	}{
+		{event.Redraw, 8, ""},
+		{event.Error, 8, "u32le: cannot read 4 bytes at 0x8"},
+	} {
+		go wm.Emit(event.Event{Type: event.JumpToPointer})
+		e := <-eventCh
+		if e.Type != testCase.typ {
+			t.Errorf("event type should be %d but got: %d (%v)", testCase.typ, e.Type, e.Error)
+		}
+		if testCase.expected != "" && (e.Error == nil || e.Error.Error() != testCase.expected) {
+			t.Errorf("error should be %q but got: %v", testCase.expected, e.Error)
+		}
+		windowStates, _, windowIndex, _ := wm.State()
+		if ws := windowStates[windowIndex]; ws.Cursor != testCase.cursor {
+			t.Errorf("cursor should be %d but got %d", testCase.cursor, ws.Cursor)
+		}
+	}
+	wm.Close()
+}
+
+func TestManagerOpenStdin(t *testing.T) {
+	wm := NewManager()
+	wm.stdin = strings.NewReader("Hello, world!")
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	if err := wm.Open("-"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	windowStates, _, windowIndex, _ := wm.State()
+	ws := windowStates[windowIndex]
+	if expected := ""; ws.Name != expected {
+		t.Errorf("name should be %q but got %q", expected, ws.Name)
+	}
+	if ws.Length != int64(13) {
+		t.Errorf("Length should be %d but got %d", int64(13), ws.Length)
+	}
+	if expected := "Hello, world!\x00"; !strings.HasPrefix(string(ws.Bytes), expected) {
+		t.Errorf("Bytes should start with %q but got %q", expected, string(ws.Bytes))
+	}
+	b := new(strings.Builder)
+	n, err := wm.WriteTo(&event.Range{
+		From: event.Absolute{Offset: 7}, To: event.Absolute{Offset: 11},
+	}, b)
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if n != 5 || b.String() != "world" {
+		t.Errorf("WriteTo should write %q but got %q", "world", b.String())
+	}
+	go wm.Emit(event.Event{Type: event.Edit, Bang: true})
+	if e := <-eventCh; e.Type != event.Error || e.Error == nil || e.Error.Error() != "no file name" {
+		t.Errorf("edit should report %q but got: %+v", "no file name", e)
+	}
+	windowStates, _, windowIndex, _ = wm.State()
+	if ws := windowStates[windowIndex]; ws.Length != int64(13) {
+		t.Errorf("Length should be %d but got %d", int64(13), ws.Length)
+	}
+	wm.Close()
+}
+
+func TestManagerFilterRead(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	dir, err := ioutil.TempDir("", "bed-test-manager-filter-read")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "input")
+	if err := ioutil.WriteFile(filename, []byte("xyz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := wm.Open(""); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+	contents := func() string {
+		b := new(strings.Builder)
+		if _, err := wm.WriteTo(nil, b); err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		return b.String()
+	}
+	for _, testCase := range []struct {
+		event    event.Event
+		typ      event.Type
+		expected string
+	}{
+		{event.Event{Type: event.Read, Arg: "!printf 'Hello, world!'"}, event.Info, "Hello, world!"},
+		{event.Event{Type: event.Filter, Range: &event.Range{
+			From: event.Absolute{Offset: 0}, To: event.Absolute{Offset: 4},
+		}, Arg: "tr a-z A-Z"}, event.Redraw, "HELLO, world!"},
+		{event.Event{Type: event.Filter, Range: &event.Range{
+			From: event.Absolute{Offset: 5}, To: event.End{},
+		}, Arg: "tr -d o"}, event.Redraw, "HELLO, wrld!"},
+		{event.Event{Type: event.Filter, Range: &event.Range{
+			From: event.Absolute{Offset: 0},
+		}, Arg: "printf H; cat"}, event.Redraw, "HHELLO, wrld!"},
+		{event.Event{Type: event.Read, Arg: filename}, event.Info, "xyzHHELLO, wrld!"},
+		{event.Event{Type: event.Filter, Range: &event.Range{
+			From: event.Absolute{Offset: 0},
+		}, Arg: "exit 1"}, event.Error, "xyzHHELLO, wrld!"},
+		{event.Event{Type: event.Read, Arg: filename + ".none"}, event.Error, "xyzHHELLO, wrld!"},
+		{event.Event{Type: event.Filter, Arg: "cat"}, event.Error, "xyzHHELLO, wrld!"},
+	} {
+		go wm.Emit(testCase.event)
+		if e := <-eventCh; e.Type != testCase.typ {
+			t.Errorf("event type should be %d but got: %d (%v)", testCase.typ, e.Type, e.Error)
+		}
+		if got := contents(); got != testCase.expected {
+			t.Errorf("contents should be %q but got %q", testCase.expected, got)
+		}
+	}
+	wm.Emit(event.Event{Type: event.Undo, Mode: mode.Normal, Count: 2})
+	<-redrawCh
+	if got, expected := contents(), "HELLO, wrld!"; got != expected {
+		t.Errorf("contents should be %q but got %q", expected, got)
+	}
+	wm.Close()
+}
+
+func TestManagerReadAppend(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	dir, err := ioutil.TempDir("", "bed-test-manager-read-append")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	input, output := filepath.Join(dir, "input"), filepath.Join(dir, "output")
+	if err := ioutil.WriteFile(input, []byte("xyz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := wm.Open(""); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+	for _, testCase := range []struct {
+		event    event.Event
+		typ      event.Type
+		expected string
+	}{
+		{event.Event{Type: event.Read, Range: &event.Range{From: event.End{}}, Arg: input}, event.Info, "xyz"},
+		{event.Event{Type: event.Read, Range: &event.Range{From: event.Absolute{Offset: 1}}, Arg: input}, event.Info, "xyxyzz"},
+		{event.Event{Type: event.Read, Range: &event.Range{From: event.Absolute{Offset: 0}}, Arg: input}, event.Info, "xyzxyxyzz"},
+		{event.Event{Type: event.Read, Range: &event.Range{From: event.End{}}, Arg: "!printf 0"}, event.Info, "xyzxyxyzz0"},
+		{event.Event{Type: event.Write, Arg: ">> " + output}, event.Error, ""},
+		{event.Event{Type: event.Write, Arg: ">> " + output, Bang: true}, event.Info, "xyzxyxyzz0"},
+		{event.Event{Type: event.Write, Range: &event.Range{
+			From: event.Absolute{Offset: 3}, To: event.Absolute{Offset: 5},
+		}, Arg: ">>" + output}, event.Info, "xyzxyxyzz0xyx"},
+	} {
+		go wm.Emit(testCase.event)
+		if e := <-eventCh; e.Type != testCase.typ {
+			t.Errorf("event type should be %d but got: %d (%v)", testCase.typ, e.Type, e.Error)
+		}
+		var got string
+		if testCase.event.Type == event.Read {
+			b := new(strings.Builder)
+			if _, err := wm.WriteTo(nil, b); err != nil {
+				t.Errorf("err should be nil but got: %v", err)
+			}
+			got = b.String()
+		} else {
+			bs, _ := ioutil.ReadFile(output)
+			got = string(bs)
+		}
+		if got != testCase.expected {
+			t.Errorf("contents should be %q but got %q", testCase.expected, got)
+		}
+	}
+	wm.Close()
+}
+
+func TestManagerWriteInPlace(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	f, err := ioutil.TempFile("", "bed-test-manager-write-in-place")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("Hello, world!"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := wm.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+	for _, testCase := range []struct {
+		events   []event.Event
+		inPlace  bool
+		expected string
+	}{
+		{[]event.Event{{Type: event.Set, Arg: "bkc=auto"}, {Type: event.Increment}}, true, "Iello, world!"},
+		{[]event.Event{{Type: event.CursorNext, Count: 7}, {Type: event.Decrement, Count: 3}}, true, "Iello, torld!"},
+		{[]event.Event{{Type: event.Set, Arg: "bkc=yes"}, {Type: event.DeleteByte}}, true, "Iello, orld!"},
+		{[]event.Event{{Type: event.Set, Arg: "bkc=auto"}, {Type: event.Increment}}, false, "Iello, prld!"},
+		{[]event.Event{{Type: event.Set, Arg: "bkc=no"}, {Type: event.Increment}}, false, "Iello, qrld!"},
+		{[]event.Event{{Type: event.Set, Arg: "bkc=yes"}}, true, "Iello, qrld!"},
+		{[]event.Event{{Type: event.DeleteByte}}, true, "Iello, rld!"},
+	} {
+		before, err := os.Stat(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range testCase.events {
+			wm.Emit(e)
+			if e.Type != event.Set {
+				<-redrawCh
+			}
+		}
+		go wm.Emit(event.Event{Type: event.Write})
+		if e := <-eventCh; e.Type != event.Info {
+			t.Errorf("event type should be %d but got: %d (%v)", event.Info, e.Type, e.Error)
+		}
+		after, err := os.Stat(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := os.SameFile(before, after); got != testCase.inPlace {
+			t.Errorf("the file should be written in place (%v) but got %v", testCase.inPlace, got)
+		}
+		bs, err := ioutil.ReadFile(f.Name())
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if string(bs) != testCase.expected {
+			t.Errorf("file contents should be %q but got %q", testCase.expected, string(bs))
+		}
+		windowStates, _, windowIndex, _ := wm.State()
+		if ws := windowStates[windowIndex]; ws.Modified || string(ws.Bytes[:ws.Length]) != testCase.expected {
+			t.Errorf("buffer should be %q without modification but got %q", testCase.expected, string(ws.Bytes[:ws.Length]))
+		}
+	}
+	for _, expected := range []string{
+		"Iello, qrld!", "Iello, prld!", "Iello, orld!", "Iello, torld!", "Iello, world!", "Hello, world!",
+	} {
+		wm.Emit(event.Event{Type: event.Undo})
+		<-redrawCh
+		windowStates, _, windowIndex, _ := wm.State()
+		if ws := windowStates[windowIndex]; string(ws.Bytes[:ws.Length]) != expected {
+			t.Errorf("buffer should be %q after undo but got %q", expected, string(ws.Bytes[:ws.Length]))
+		}
+	}
+	wm.Close()
+}
+
+func TestManagerWriteSymlink(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	dir, err := ioutil.TempDir("", "bed-test-manager-write-symlink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename, link := filepath.Join(dir, "file"), filepath.Join(dir, "link")
+	if err := ioutil.WriteFile(filename, []byte("Hello, world!"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filename, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := wm.Open(link); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+	wm.Emit(event.Event{Type: event.Increment})
+	<-redrawCh
+	go wm.Emit(event.Event{Type: event.Write})
+	if e := <-eventCh; e.Type != event.Info {
+		t.Errorf("event type should be %d but got: %d (%v)", event.Info, e.Type, e.Error)
+	}
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("the symbolic link should be kept")
+	}
+	if info, err = os.Stat(filename); err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("the permission should be %o but got %o", 0600, info.Mode().Perm())
+	}
+	bs, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "Iello, world!"; string(bs) != expected {
+		t.Errorf("file contents should be %q but got %q", expected, string(bs))
+	}
+	wm.Close()
+}
+
+func TestManagerWriteBackup(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	dir, err := ioutil.TempDir("", "bed-test-manager-write-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename, backupDir := filepath.Join(dir, "file"), filepath.Join(dir, "backup")
+	if err := ioutil.WriteFile(filename, []byte("Hello, world!"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filename+".~", nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(backupDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := wm.Open(filename); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+	for _, testCase := range []struct {
+		set      string
+		expected string
+		backups  map[string]string
+	}{
+		{"backup", "Iello, world!", map[string]string{"file~": "Hello, world!"}},
+		{"bkn", "Jello, world!", map[string]string{"file.~1~": "Iello, world!", "file.~2~": ""}},
+		{"", "Kello, world!", map[string]string{"file.~1~": "Iello, world!", "file.~2~": "Jello, world!"}},
+		{"nobkn bdir=" + backupDir + " bex=.bak", "Lello, world!", map[string]string{"backup/file.bak": "Kello, world!"}},
+		{"bdir=" + backupDir + "//", "Mello, world!", map[string]string{
+			"backup/" + strings.Replace(filename, "/", "%", -1) + ".bak": "Lello, world!",
+		}},
+		{"nobk wb bkc=yes bdir=. bex=~", "Nello, world!", map[string]string{"file~": ""}},
+		{"bk", "Oello, world!", map[string]string{"file~": "Nello, world!"}},
+	} {
+		if testCase.set != "" {
+			wm.Emit(event.Event{Type: event.Set, Arg: testCase.set})
+		}
+		wm.Emit(event.Event{Type: event.Increment})
+		<-redrawCh
+		go wm.Emit(event.Event{Type: event.Write})
+		if e := <-eventCh; e.Type != event.Info {
+			t.Errorf("event type should be %d but got: %d (%v)", event.Info, e.Type, e.Error)
+		}
+		bs, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if string(bs) != testCase.expected {
+			t.Errorf("file contents should be %q but got %q", testCase.expected, string(bs))
+		}
+		for name, expected := range testCase.backups {
+			bs, err := ioutil.ReadFile(filepath.Join(dir, name))
+			if expected == "" {
+				if !os.IsNotExist(err) {
+					t.Errorf("%s should not exist but got: %v", name, err)
+				}
+			} else if string(bs) != expected {
+				t.Errorf("%s contents should be %q but got %q (%v)", name, expected, string(bs), err)
+			}
+		}
+	}
+	wm.Close()
+}
+
+func TestManagerWriteReadOnlyDirectory(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	dir, err := ioutil.TempDir("", "bed-test-manager-write-read-only-directory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename, other := filepath.Join(dir, "file"), filepath.Join(dir, "other")
+	if err := ioutil.WriteFile(filename, []byte("Hello, world!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(other, []byte("Hello, world!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := wm.Open(filename); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(dir, 0755)
+	_, _, _, _ = wm.State()
+	wm.Emit(event.Event{Type: event.DeleteByte})
+	<-redrawCh
+	for _, name := range []string{"", other} {
+		go wm.Emit(event.Event{Type: event.Write, Arg: name})
+		if e := <-eventCh; e.Type != event.Info {
+			t.Errorf("event type should be %d but got: %d (%v)", event.Info, e.Type, e.Error)
+		}
+	}
+	for _, name := range []string{filename, other} {
+		bs, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if expected := "ello, world!"; string(bs) != expected {
+			t.Errorf("file contents should be %q but got %q", expected, string(bs))
+		}
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fis) != 2 {
+		t.Errorf("temporary files should not be left but got %d files", len(fis))
+	}
+	wm.Close()
+}
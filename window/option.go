@@ -0,0 +1,302 @@
+package window
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/itchyny/bed/event"
+)
+
+// options holds the values of the options.
+// Each window has its own copy for the window-local options,
+// and each buffer has its own copy for the buffer-local options.
+type options struct {
+	jumpFormat     string
+	jumpBase       int64
+	scrollBind     bool
+	scrollAlign    string
+	diff           bool
+	diffAlign      bool
+	readonly       bool
+	modifiable     bool
+	backupCopy     string
+	backup         bool
+	writeBackup    bool
+	backupDir      string
+	backupExt      string
+	backupNumbered bool
+	watch          bool
+}
+
+func defaultOptions() options {
+	return options{
+		jumpFormat: "u32le", scrollAlign: "absolute", modifiable: true,
+		backupCopy: "no", backupDir: ".", backupExt: "~",
+	}
+}
+
+type option struct {
+	name    string
+	abbr    string
+	boolean bool
+	local   bool
+	buffer  bool
+	get     func(*options) string
+	set     func(*options, string) error
+}
+
+var optionList = []option{
+	{
+		name: "jumpformat", abbr: "jf", local: true,
+		get: func(o *options) string { return o.jumpFormat },
+		set: func(o *options, value string) error {
+			switch value {
+			case "u8", "u16le", "u16be", "u32le", "u32be", "u64le", "u64be":
+				o.jumpFormat = value
+				return nil
+			default:
+				return fmt.Errorf("invalid value for jumpformat: %s", value)
+			}
+		},
+	},
+	{
+		name: "jumpbase", abbr: "jb", local: true,
+		get: func(o *options) string { return fmt.Sprintf("0x%x", o.jumpBase) },
+		set: func(o *options, value string) error {
+			n, err := strconv.ParseInt(value, 0, 64)
+			if err != nil {
+				return fmt.Errorf("invalid value for jumpbase: %s", value)
+			}
+			o.jumpBase = n
+			return nil
+		},
+	},
+	{
+		name: "scrollbind", abbr: "scb", boolean: true, local: true,
+		get: func(o *options) string { return strconv.FormatBool(o.scrollBind) },
+		set: func(o *options, value string) error {
+			o.scrollBind = value == "true"
+			return nil
+		},
+	},
+	{
+		name: "scrollalign", abbr: "sca",
+		get: func(o *options) string { return o.scrollAlign },
+		set: func(o *options, value string) error {
+			switch value {
+			case "absolute", "relative":
+				o.scrollAlign = value
+				return nil
+			default:
+				return fmt.Errorf("invalid value for scrollalign: %s", value)
+			}
+		},
+	},
+	{
+		name: "diff", boolean: true, local: true,
+		get: func(o *options) string { return strconv.FormatBool(o.diff) },
+		set: func(o *options, value string) error {
+			o.diff = value == "true"
+			return nil
+		},
+	},
+	{
+		name: "diffalign", abbr: "dal", boolean: true,
+		get: func(o *options) string { return strconv.FormatBool(o.diffAlign) },
+		set: func(o *options, value string) error {
+			o.diffAlign = value == "true"
+			return nil
+		},
+	},
+	{
+		name: "readonly", abbr: "ro", boolean: true, buffer: true,
+		get: func(o *options) string { return strconv.FormatBool(o.readonly) },
+		set: func(o *options, value string) error {
+			o.readonly = value == "true"
+			return nil
+		},
+	},
+	{
+		name: "modifiable", abbr: "ma", boolean: true, buffer: true,
+		get: func(o *options) string { return strconv.FormatBool(o.modifiable) },
+		set: func(o *options, value string) error {
+			o.modifiable = value == "true"
+			return nil
+		},
+	},
+	{
+		name: "backupcopy", abbr: "bkc",
+		get: func(o *options) string { return o.backupCopy },
+		set: func(o *options, value string) error {
+			switch value {
+			case "yes", "no", "auto":
+				o.backupCopy = value
+				return nil
+			default:
+				return fmt.Errorf("invalid value for backupcopy: %s", value)
+			}
+		},
+	},
+	{
+		name: "backup", abbr: "bk", boolean: true,
+		get: func(o *options) string { return strconv.FormatBool(o.backup) },
+		set: func(o *options, value string) error {
+			o.backup = value == "true"
+			return nil
+		},
+	},
+	{
+		name: "writebackup", abbr: "wb", boolean: true,
+		get: func(o *options) string { return strconv.FormatBool(o.writeBackup) },
+		set: func(o *options, value string) error {
+			o.writeBackup = value == "true"
+			return nil
+		},
+	},
+	{
+		name: "backupdir", abbr: "bdir",
+		get: func(o *options) string { return o.backupDir },
+		set: func(o *options, value string) error {
+			if value == "" {
+				return errors.New("backupdir must not be empty")
+			}
+			o.backupDir = value
+			return nil
+		},
+	},
+	{
+		name: "backupext", abbr: "bex",
+		get: func(o *options) string { return o.backupExt },
+		set: func(o *options, value string) error {
+			if value == "" || strings.ContainsRune(value, filepath.Separator) {
+				return fmt.Errorf("invalid value for backupext: %s", value)
+			}
+			o.backupExt = value
+			return nil
+		},
+	},
+	{
+		name: "backupnumbered", abbr: "bkn", boolean: true,
+		get: func(o *options) string { return strconv.FormatBool(o.backupNumbered) },
+		set: func(o *options, value string) error {
+			o.backupNumbered = value == "true"
+			return nil
+		},
+	},
+	{
+		name: "watch", boolean: true,
+		get: func(o *options) string { return strconv.FormatBool(o.watch) },
+		set: func(o *options, value string) error {
+			o.watch = value == "true"
+			return nil
+		},
+	},
+}
+
+func lookupOption(name string) (option, error) {
+	for _, o := range optionList {
+		if o.name == name || o.abbr != "" && o.abbr == name {
+			return o, nil
+		}
+	}
+	return option{}, fmt.Errorf("unknown option: %s", name)
+}
+
+func (m *Manager) set(e event.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	window := m.windows[m.windowIndex]
+	window.mu.Lock()
+	defer window.mu.Unlock()
+	var infos []string
+	args := strings.FieldsFunc(e.Arg, unicode.IsSpace)
+	if len(args) == 0 {
+		for _, o := range optionList {
+			infos = append(infos, m.showOption(o, window))
+		}
+	}
+	for _, arg := range args {
+		info, err := m.setOption(arg, window)
+		if err != nil {
+			return err
+		}
+		if info != "" {
+			infos = append(infos, info)
+		}
+	}
+	if len(infos) > 0 {
+		m.eventCh <- event.Event{Type: event.Info, Error: errors.New(strings.Join(infos, "  "))}
+	}
+	return nil
+}
+
+func (m *Manager) setOption(arg string, window *window) (string, error) {
+	name, value, hasValue := arg, "", false
+	if i := strings.IndexAny(arg, "=:"); i > 0 {
+		name, value, hasValue = arg[:i], arg[i+1:], true
+	}
+	if strings.HasSuffix(name, "?") {
+		o, err := lookupOption(strings.TrimSuffix(name, "?"))
+		if err != nil {
+			return "", err
+		}
+		return m.showOption(o, window), nil
+	}
+	o, err := lookupOption(name)
+	if err != nil && !hasValue {
+		var prefix, rest string
+		if strings.HasPrefix(name, "no") {
+			prefix, rest = "no", name[2:]
+		} else if strings.HasPrefix(name, "inv") {
+			prefix, rest = "inv", name[3:]
+		} else if strings.HasSuffix(name, "!") {
+			prefix, rest = "inv", name[:len(name)-1]
+		}
+		if o, err = lookupOption(rest); err != nil || !o.boolean {
+			return "", fmt.Errorf("unknown option: %s", name)
+		}
+		value = strconv.FormatBool(prefix != "no")
+		if prefix == "inv" {
+			value = strconv.FormatBool(o.get(m.optionsFor(o, window)) != "true")
+		}
+		return "", o.set(m.optionsFor(o, window), value)
+	}
+	if err != nil {
+		return "", err
+	}
+	if o.boolean {
+		if hasValue {
+			return "", fmt.Errorf("invalid argument: %s", arg)
+		}
+		return "", o.set(m.optionsFor(o, window), "true")
+	}
+	if !hasValue {
+		return m.showOption(o, window), nil
+	}
+	return "", o.set(m.optionsFor(o, window), value)
+}
+
+func (m *Manager) optionsFor(o option, window *window) *options {
+	if o.buffer {
+		return &window.buf.options
+	}
+	if o.local {
+		return &window.options
+	}
+	return &m.options
+}
+
+func (m *Manager) showOption(o option, window *window) string {
+	value := o.get(m.optionsFor(o, window))
+	if o.boolean {
+		if value == "true" {
+			return o.name
+		}
+		return "no" + o.name
+	}
+	return o.name + "=" + value
+}
@@ -0,0 +1,49 @@
+package window
+
+import "github.com/itchyny/bed/diff"
+
+// scrollBind holds the position of the window at the last synchronization.
+type scrollBind struct {
+	bound  bool
+	offset int64
+	cursor int64
+}
+
+// syncScrollBind moves the scroll-bound windows in the active tab page along
+// with the active window. With the relative alignment, the windows keep the
+// differences of the positions at the time of binding. The windows in diff
+// mode are aligned by the differences of the buffers.
+func (m *Manager) syncScrollBind() {
+	active := m.windows[m.windowIndex]
+	active.mu.Lock()
+	bound, prev := active.options.scrollBind, active.scrollBind
+	offset, cursor := active.offset, active.cursor
+	active.scrollBind = scrollBind{bound, offset, cursor}
+	active.mu.Unlock()
+	moved := bound && prev.bound && (prev.offset != offset || prev.cursor != cursor)
+	for i := range m.layout.Collect() {
+		window := m.windows[i]
+		if window == active {
+			continue
+		}
+		window.mu.Lock()
+		if !window.options.scrollBind {
+			window.scrollBind.bound = false
+		} else {
+			if moved && window.scrollBind.bound {
+				if m.diff.a == active.buf && m.diff.b == window.buf ||
+					m.diff.a == window.buf && m.diff.b == active.buf {
+					reverse := m.diff.b == active.buf
+					window.scrollTo(diff.Map(m.diff.hunks, offset, reverse),
+						diff.Map(m.diff.hunks, cursor, reverse))
+				} else if m.options.scrollAlign == "relative" {
+					window.scrollTo(window.offset+offset-prev.offset, window.cursor+cursor-prev.cursor)
+				} else {
+					window.scrollTo(offset, cursor)
+				}
+			}
+			window.scrollBind = scrollBind{true, window.offset, window.cursor}
+		}
+		window.mu.Unlock()
+	}
+}
@@ -0,0 +1,314 @@
+package window
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+const swapHeader = "bed swap file\n"
+
+// The operations recorded in the swap file.
+const (
+	swapInsert       byte = 'i'
+	swapReplace      byte = 'r'
+	swapDelete       byte = 'd'
+	swapInsertBytes  byte = 'I'
+	swapReplaceBytes byte = 'R'
+	swapDeleteBytes  byte = 'D'
+	swapPush         byte = 'p'
+	swapUndo         byte = 'u'
+	swapRedo         byte = 'U'
+)
+
+// swapFile journals the edit operations on a buffer so that the changes can
+// be recovered after a crash (bed -r file). The file is created on the first
+// change, and removed when the changes are written or discarded.
+type swapFile struct {
+	name    string
+	file    *os.File
+	pending []byte
+	err     error
+}
+
+// SwapFileName returns the name of the swap file for the file.
+func SwapFileName(filename string) string {
+	return filepath.Join(filepath.Dir(filename), "."+filepath.Base(filename)+".bed.swp")
+}
+
+// swapFileNames returns the names of the swap files for the file. When the
+// swap file is left by another session, the changes are journaled to the
+// next name in the order of .swp, .swo, .swn, ..., .swa.
+func swapFileNames(filename string) []string {
+	name := SwapFileName(filename)
+	names := make([]string, 0, 'p'-'a'+1)
+	for c := 'p'; c >= 'a'; c-- {
+		names = append(names, name[:len(name)-1]+string(c))
+	}
+	return names
+}
+
+// swapFileTarget returns the name of the file the swap file is for,
+// or the empty string if the name is not of a swap file.
+func swapFileTarget(name string) string {
+	base := filepath.Base(name)
+	if len(base) <= len("..bed.swp") || base[0] != '.' ||
+		!strings.HasSuffix(base[:len(base)-1], ".bed.sw") ||
+		base[len(base)-1] < 'a' || 'p' < base[len(base)-1] {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(name), base[1:len(base)-len(".bed.swp")])
+}
+
+// write records the operation. The records are written on flush.
+func (s *swapFile) write(op byte, args ...int64) {
+	if s == nil || s.err != nil {
+		return
+	}
+	s.pending = append(s.pending, op)
+	for _, x := range args {
+		var bs [binary.MaxVarintLen64]byte
+		s.pending = append(s.pending, bs[:binary.PutUvarint(bs[:], uint64(x))]...)
+	}
+}
+
+// writeBytes records the operation with the offset and the bytes. The
+// bytes are written to the swap file at once, not to keep them pending.
+func (s *swapFile) writeBytes(op byte, offset int64, bs []byte) {
+	s.write(op, offset, int64(len(bs)))
+	s.flush()
+	if s == nil || s.err != nil {
+		return
+	}
+	_, s.err = s.file.Write(bs)
+}
+
+// flush writes the pending records to the swap file. Journaling stops
+// on errors, the editing itself should not fail for the swap file.
+func (s *swapFile) flush() {
+	if s == nil || s.err != nil || len(s.pending) == 0 {
+		return
+	}
+	if s.file == nil {
+		if s.file, s.err = os.OpenFile(s.name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600); s.err != nil {
+			return
+		}
+		if _, s.err = s.file.WriteString(swapHeader); s.err != nil {
+			return
+		}
+	}
+	_, s.err = s.file.Write(s.pending)
+	s.pending = s.pending[:0]
+}
+
+// remove deletes the swap file. It is created again on the next change.
+func (s *swapFile) remove() {
+	if s == nil {
+		return
+	}
+	s.pending, s.err = nil, nil
+	if s.file != nil {
+		s.file.Close()
+		os.Remove(s.name)
+		s.file = nil
+	}
+}
+
+// Recover sets the file to be recovered from the swap file on opening. The
+// name of a swap file (.file.bed.swo) can be given to recover from it, and
+// the name of the file to open is returned.
+func (m *Manager) Recover(filename string) (string, error) {
+	name, err := homedir.Expand(filename)
+	if err != nil {
+		return "", err
+	}
+	if name, err = filepath.Abs(name); err != nil {
+		return "", err
+	}
+	swapName := SwapFileName(name)
+	if target := swapFileTarget(name); target != "" {
+		swapName, name = name, target
+		filename = filepath.Join(filepath.Dir(filename), filepath.Base(target))
+	}
+	if m.recoverFiles == nil {
+		m.recoverFiles = make(map[string]string)
+	}
+	m.recoverFiles[name] = swapName
+	return filename, nil
+}
+
+// setSwap prepares the swap file of the buffer. When the swap file exists,
+// the changes are recovered from it if requested. Otherwise the swap file is
+// left for the other session or the later recovery, and the buffer is
+// journaled to the next swap file name. The buffer is not journaled only when
+// all the names are used.
+func (m *Manager) setSwap(b *fileBuffer) error {
+	if name, ok := m.recoverFiles[b.filename]; ok {
+		if _, err := os.Stat(name); err == nil {
+			return b.recover(name)
+		}
+	}
+	for i, name := range swapFileNames(b.filename) {
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			b.swap = &swapFile{name: name}
+			b.swapExists = i > 0
+			return nil
+		}
+	}
+	b.swapExists = true
+	return nil
+}
+
+// recover replays the operations in the swap file onto the buffer, and
+// continues journaling to the swap file.
+func (b *fileBuffer) recover(name string) error {
+	f, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(f)
+	header := make([]byte, len(swapHeader))
+	if _, err := io.ReadFull(r, header); err != nil || string(header) != swapHeader {
+		f.Close()
+		return fmt.Errorf("%s is not a swap file", name)
+	}
+	pos := int64(len(swapHeader))
+	for {
+		n, err := b.replay(r)
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break // the last record can be incomplete on crash
+			}
+			f.Close()
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		pos += n
+	}
+	// drop the incomplete record to append the records after it
+	if err := f.Truncate(pos); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(pos, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	b.swap = &swapFile{name: name, file: f}
+	return nil
+}
+
+var errBrokenSwap = errors.New("broken swap file")
+
+// replay reads an operation and applies it to the buffer.
+// It returns the number of bytes of the record.
+func (b *fileBuffer) replay(r *bufio.Reader) (int64, error) {
+	op, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	var argc int
+	switch op {
+	case swapInsert, swapReplace, swapInsertBytes, swapReplaceBytes, swapDeleteBytes, swapPush:
+		argc = 2
+	case swapDelete:
+		argc = 1
+	case swapUndo, swapRedo:
+	default:
+		return 0, errBrokenSwap
+	}
+	n, args := int64(1), make([]int64, argc)
+	for i := range args {
+		x, err := binary.ReadUvarint(countReader{r, &n})
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		if x > math.MaxInt64 {
+			return 0, errBrokenSwap
+		}
+		args[i] = int64(x)
+	}
+	var bs []byte
+	switch op {
+	case swapInsertBytes, swapReplaceBytes:
+		// read the bytes as they come, not to allocate for a broken length
+		var buf bytes.Buffer
+		m, err := io.CopyN(&buf, r, args[1])
+		n += m
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		bs = buf.Bytes()
+	}
+	length, err := b.buffer.Len()
+	if err != nil {
+		return 0, err
+	}
+	switch op {
+	case swapInsert, swapReplace:
+		if args[0] > length || args[1] > 0xff {
+			return 0, errBrokenSwap
+		}
+		// replacing at the end appends the byte in the replace mode,
+		// which is applied as an insertion to extend the length
+		if op == swapInsert || args[0] == length {
+			b.insert(args[0], byte(args[1]))
+		} else {
+			b.replace(args[0], byte(args[1]))
+		}
+	case swapDelete:
+		if args[0] >= length {
+			return 0, errBrokenSwap
+		}
+		b.delete(args[0])
+	case swapInsertBytes, swapReplaceBytes:
+		if args[0] > length {
+			return 0, errBrokenSwap
+		}
+		if op == swapInsertBytes {
+			b.insertBytes(args[0], bs)
+		} else {
+			b.replaceBytes(args[0], bs)
+		}
+	case swapDeleteBytes:
+		if args[0] > length || args[1] > length-args[0] {
+			return 0, errBrokenSwap
+		}
+		b.deleteBytes(args[0], args[1])
+	case swapPush:
+		b.push(args[0], args[1])
+	case swapUndo:
+		b.undo()
+	case swapRedo:
+		b.redo()
+	}
+	return n, nil
+}
+
+// countReader counts the bytes read.
+type countReader struct {
+	r *bufio.Reader
+	n *int64
+}
+
+func (r countReader) ReadByte() (byte, error) {
+	c, err := r.r.ReadByte()
+	if err == nil {
+		*r.n++
+	}
+	return c, err
+}
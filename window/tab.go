@@ -0,0 +1,179 @@
+package window
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/layout"
+	"github.com/itchyny/bed/mathutil"
+)
+
+// tabPage holds the layout of the tab page which is not active.
+// The active tab page is held by the Manager.
+type tabPage struct {
+	layout          layout.Layout
+	windowIndex     int
+	prevWindowIndex int
+}
+
+func (m *Manager) saveTabPage() {
+	m.tabPages[m.tabIndex] = tabPage{m.layout, m.windowIndex, m.prevWindowIndex}
+}
+
+func (m *Manager) loadTabPage(index int) {
+	t := m.tabPages[index]
+	m.tabIndex = index
+	m.layout, m.windowIndex, m.prevWindowIndex = m.fitLayout(t.layout), t.windowIndex, t.prevWindowIndex
+}
+
+// tablineHeight returns the height of the tabline,
+// which is shown when there are multiple tab pages.
+func (m *Manager) tablineHeight() int {
+	if len(m.tabPages) > 1 {
+		return 1
+	}
+	return 0
+}
+
+// fitLayout resizes the layout to fit the screen below the tabline.
+func (m *Manager) fitLayout(l layout.Layout) layout.Layout {
+	h := m.tablineHeight()
+	return l.Resize(0, h, m.width, m.height-h)
+}
+
+func (m *Manager) tabNew(e event.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.addTabPage(e.Arg, m.tabIndex+1); err != nil {
+		return err
+	}
+	m.loadTabPage(m.tabIndex + 1)
+	return nil
+}
+
+// AddTabPage opens a new tab page after the last tab page.
+func (m *Manager) AddTabPage(filename string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.addTabPage(filename, len(m.tabPages)); err != nil {
+		return err
+	}
+	m.layout = m.fitLayout(m.layout)
+	return nil
+}
+
+// addTabPage inserts a tab page at the index without switching to it.
+func (m *Manager) addTabPage(filename string, index int) error {
+	window, err := m.open(filename)
+	if err != nil {
+		return err
+	}
+	window.options = m.windows[m.windowIndex].options
+	go window.run()
+	m.windows = append(m.windows, window)
+	i := len(m.windows) - 1
+	m.saveTabPage()
+	m.tabPages = append(m.tabPages[:index], append([]tabPage{
+		{layout.NewLayout(i), i, i},
+	}, m.tabPages[index:]...)...)
+	return nil
+}
+
+func (m *Manager) tabClose(e event.Event) error {
+	if len(e.Arg) > 0 {
+		return fmt.Errorf("too many arguments for %s", e.CmdName)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.tabPages) == 1 {
+		return errors.New("cannot close last tab page")
+	}
+	var indices []int
+	for i := range m.layout.Collect() {
+		indices = append(indices, i)
+	}
+	if err := m.checkAbandon(e.Bang, indices...); err != nil {
+		return err
+	}
+	m.closeTabPage()
+	return nil
+}
+
+// closeTabPage closes all the windows in the active tab page.
+func (m *Manager) closeTabPage() {
+	for i := range m.layout.Collect() {
+		m.removeBufferWindow(i)
+	}
+	m.tabPages = append(m.tabPages[:m.tabIndex], m.tabPages[m.tabIndex+1:]...)
+	m.loadTabPage(mathutil.MinInt(m.tabIndex, len(m.tabPages)-1))
+}
+
+// tabNext goes to the next tab page, or to the count-th tab page.
+func (m *Manager) tabNext(e event.Event) error {
+	count, err := tabCount(e)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	index := (m.tabIndex + 1) % len(m.tabPages)
+	if count > 0 {
+		if count > int64(len(m.tabPages)) {
+			return fmt.Errorf("tab page %d does not exist", count)
+		}
+		index = int(count) - 1
+	}
+	m.saveTabPage()
+	m.loadTabPage(index)
+	return nil
+}
+
+// tabPrev goes back to the previous tab page by the count.
+func (m *Manager) tabPrev(e event.Event) error {
+	count, err := tabCount(e)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := int64(len(m.tabPages))
+	index := ((int64(m.tabIndex)-mathutil.MaxInt64(count, 1))%n + n) % n
+	m.saveTabPage()
+	m.loadTabPage(int(index))
+	return nil
+}
+
+func tabCount(e event.Event) (int64, error) {
+	if len(e.Arg) == 0 {
+		return e.Count, nil
+	}
+	count, err := strconv.ParseInt(e.Arg, 10, 64)
+	if err != nil || count <= 0 {
+		return 0, fmt.Errorf("invalid argument for %s: %s", e.CmdName, e.Arg)
+	}
+	return count, nil
+}
+
+// TabPages returns the names of the tab pages and the active index.
+func (m *Manager) TabPages() ([]string, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.tabPages) == 0 {
+		return nil, 0
+	}
+	m.saveTabPage()
+	names := make([]string, len(m.tabPages))
+	for i, t := range m.tabPages {
+		name := m.windows[t.windowIndex].buf.name
+		if name == "" {
+			name = "[No Name]"
+		}
+		if n := len(t.layout.Collect()); n > 1 {
+			name = strconv.Itoa(n) + " " + name
+		}
+		names[i] = name
+	}
+	return names, m.tabIndex
+}
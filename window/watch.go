@@ -0,0 +1,93 @@
+package window
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// watchInterval is the interval to check the files with the watch option.
+var watchInterval = time.Second
+
+// CheckChanged returns an error to warn that the file of a buffer in the
+// active tab page is changed on the disk by another process, or has the
+// swap file left by another session. Each change is warned only once.
+// The files are checked when the active buffer is switched, when the watch
+// option detects a change, or at most once in the interval otherwise, not to
+// stat the files on every redraw.
+func (m *Manager) CheckChanged() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.layout == nil {
+		return nil
+	}
+	for i := range m.layout.Collect() {
+		if b := m.windows[i].buf; b.swapExists {
+			b.swapExists = false
+			if b.swap == nil {
+				return fmt.Errorf("%s: found too many swap files, the changes are not journaled (delete the old ones listed by bed -r)", b.name)
+			}
+			return fmt.Errorf("%s: found a swap file, the changes are saved to %s instead", b.name, filepath.Base(b.swap.name))
+		}
+	}
+	active, now := m.windows[m.windowIndex].buf, time.Now()
+	if active == m.checkedBuffer && now.Sub(m.checkedTime) < watchInterval {
+		return nil
+	}
+	m.checkedBuffer, m.checkedTime = active, now
+	if b := m.changedBuffer(true); b != nil {
+		return fmt.Errorf("%s: file changed on disk (:e! to reload, :w! to overwrite)", b.name)
+	}
+	return nil
+}
+
+// changedBuffer returns the buffer in the active tab page whose file is
+// changed on the disk and the change is not warned yet.
+func (m *Manager) changedBuffer(warn bool) *fileBuffer {
+	if m.layout == nil {
+		return nil
+	}
+	for i := range m.layout.Collect() {
+		b := m.windows[i].buf
+		if info, changed := b.changedOnDisk(); changed {
+			b.mu.Lock()
+			warned := sameStat(b.warnedStat, info)
+			if warn {
+				b.warnedStat = info
+			}
+			b.mu.Unlock()
+			if !warned {
+				return b
+			}
+		}
+	}
+	return nil
+}
+
+// watch checks the files periodically while the watch option is set,
+// and requests to redraw the screen so that the changes are warned.
+func (m *Manager) watch() {
+	defer close(m.watchStopped)
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.mu.Lock()
+			changed := m.options.watch && m.changedBuffer(false) != nil
+			if changed {
+				m.checkedTime = time.Time{}
+			}
+			m.mu.Unlock()
+			if changed {
+				select {
+				case m.redrawCh <- struct{}{}:
+				case <-m.done:
+					return
+				}
+			}
+		}
+	}
+}
@@ -3,14 +3,15 @@ package window
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
+	"sort"
 	"strconv"
 	"sync"
 	"unicode/utf8"
 
-	"github.com/itchyny/bed/buffer"
 	"github.com/itchyny/bed/event"
-	"github.com/itchyny/bed/history"
+	"github.com/itchyny/bed/expr"
 	"github.com/itchyny/bed/mathutil"
 	"github.com/itchyny/bed/mode"
 	"github.com/itchyny/bed/state"
@@ -19,18 +20,18 @@ import (
 var pageUpDownJumpRatio = 0.95
 
 type window struct {
-	buffer      *buffer.Buffer
+	buf         *fileBuffer
 	changedTick uint64
+	syncedTick  uint64
 	prevChanged bool
-	history     *history.History
-	filename    string
-	name        string
 	height      int64
 	width       int64
 	offset      int64
 	cursor      int64
 	length      int64
 	stack       []position
+	options     options
+	scrollBind  scrollBind
 	append      bool
 	replaceByte bool
 	extending   bool
@@ -54,26 +55,74 @@ type readAtSeeker interface {
 }
 
 func newWindow(r readAtSeeker, filename string, name string, redrawCh chan<- struct{}) (*window, error) {
-	buffer := buffer.NewBuffer(r)
-	length, err := buffer.Len()
+	b, err := newFileBuffer(r, filename, name)
+	if err != nil {
+		return nil, err
+	}
+	return newBufferWindow(b, redrawCh)
+}
+
+func newBufferWindow(b *fileBuffer, redrawCh chan<- struct{}) (*window, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	length, err := b.buffer.Len()
 	if err != nil {
 		return nil, err
 	}
-	history := history.NewHistory()
-	history.Push(buffer, 0, 0)
 	return &window{
-		buffer:      buffer,
-		history:     history,
-		filename:    filename,
-		name:        name,
+		buf:         b,
+		syncedTick:  b.changedTick,
 		length:      length,
+		options:     defaultOptions(),
 		visualStart: -1,
 		redrawCh:    redrawCh,
 		eventCh:     make(chan event.Event),
-		mu:          new(sync.Mutex),
+		mu:          b.mu,
 	}, nil
 }
 
+// split creates a new window viewing the same buffer at the same position.
+func (w *window) split() (*window, error) {
+	window, err := newBufferWindow(w.buf, w.redrawCh)
+	if err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	window.offset, window.options, window.stack = w.offset, w.options, append([]position{}, w.stack...)
+	window.cursor = mathutil.MinInt64(w.cursor, mathutil.MaxInt64(window.length, 1)-1)
+	return window, nil
+}
+
+// scrollTo moves the offset and the cursor for the scroll binding.
+func (w *window) scrollTo(offset, cursor int64) {
+	w.cursor = mathutil.MaxInt64(mathutil.MinInt64(cursor, mathutil.MaxInt64(w.length, 1)-1), 0)
+	w.offset = mathutil.MaxInt64(mathutil.MinInt64(offset, w.cursor), 0)
+	if w.width > 0 {
+		w.offset = w.offset / w.width * w.width
+	}
+}
+
+// sync updates the length and the cursor on changes by other windows
+// viewing the same buffer.
+func (w *window) sync() {
+	if w.syncedTick == w.buf.changedTick {
+		return
+	}
+	w.syncedTick = w.buf.changedTick
+	w.length, _ = w.buf.buffer.Len()
+	if w.extending {
+		w.length++
+	}
+	w.cursor = mathutil.MaxInt64(mathutil.MinInt64(w.cursor, mathutil.MaxInt64(w.length, 1)-1), 0)
+	if w.visualStart >= w.length {
+		w.visualStart = mathutil.MaxInt64(w.length, 1) - 1
+	}
+	if w.width > 0 && w.cursor < w.offset {
+		w.offset = w.cursor / w.width * w.width
+	}
+}
+
 func (w *window) setSize(width, height int) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -91,6 +140,7 @@ func (w *window) setSize(width, height int) {
 func (w *window) run() {
 	for e := range w.eventCh {
 		w.mu.Lock()
+		w.sync()
 		offset, cursor, changedTick := w.offset, w.cursor, w.changedTick
 		switch e.Type {
 		case event.CursorUp:
@@ -131,6 +181,10 @@ func (w *window) run() {
 			w.jumpTo()
 		case event.JumpBack:
 			w.jumpBack()
+		case event.GotoMark:
+			w.gotoMark(e.Rune)
+		case event.SetMark:
+			w.setMark(e.Rune)
 
 		case event.DeleteByte:
 			w.deleteByte(e.Count)
@@ -197,13 +251,14 @@ func (w *window) run() {
 		changed := changedTick != w.changedTick
 		if e.Type != event.Undo && e.Type != event.Redo {
 			if e.Mode == mode.Normal && changed || e.Type == event.ExitInsert && w.prevChanged {
-				w.history.Push(w.buffer, w.offset, w.cursor)
+				w.buf.push(w.offset, w.cursor)
 			} else if e.Mode != mode.Normal && w.prevChanged && !changed &&
-				event.CursorUp <= e.Type && e.Type <= event.JumpBack {
-				w.history.Push(w.buffer, offset, cursor)
+				event.CursorUp <= e.Type && e.Type <= event.GotoMark {
+				w.buf.push(offset, cursor)
 			}
 		}
 		w.prevChanged = changed
+		w.buf.swap.flush()
 		w.mu.Unlock()
 		w.redrawCh <- struct{}{}
 	}
@@ -211,7 +266,7 @@ func (w *window) run() {
 
 func (w *window) readBytes(offset int64, len int) (int, []byte, error) {
 	bytes := make([]byte, len)
-	n, err := w.buffer.ReadAt(bytes, offset)
+	n, err := w.buf.buffer.ReadAt(bytes, offset)
 	if err != nil && err != io.EOF {
 		return 0, bytes, err
 	}
@@ -222,26 +277,37 @@ func (w *window) writeTo(r *event.Range, dst io.Writer) (int64, error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	if r == nil {
-		if _, err := w.buffer.Seek(0, io.SeekStart); err != nil {
+		if _, err := w.buf.buffer.Seek(0, io.SeekStart); err != nil {
 			return 0, err
 		}
-		return io.Copy(dst, w.buffer)
+		return io.Copy(dst, w.buf.buffer)
 	}
-	var from, to int64
-	var err error
-	if from, err = w.positionToOffset(r.From); err != nil {
+	from, to, err := w.rangeToOffsets(r)
+	if err != nil {
 		return 0, err
 	}
-	if to, err = w.positionToOffset(r.To); err != nil {
+	if _, err := w.buf.buffer.Seek(from, io.SeekStart); err != nil {
 		return 0, err
 	}
+	return io.Copy(dst, io.LimitReader(w.buf.buffer, to-from+1))
+}
+
+// rangeToOffsets returns the offsets of the both ends of the range.
+func (w *window) rangeToOffsets(r *event.Range) (int64, int64, error) {
+	from, err := w.positionToOffset(r.From)
+	if err != nil {
+		return 0, 0, err
+	}
+	to := from
+	if r.To != nil {
+		if to, err = w.positionToOffset(r.To); err != nil {
+			return 0, 0, err
+		}
+	}
 	if from > to {
 		from, to = to, from
 	}
-	if _, err := w.buffer.Seek(from, io.SeekStart); err != nil {
-		return 0, err
-	}
-	return io.Copy(dst, io.LimitReader(w.buffer, to-from+1))
+	return from, to, nil
 }
 
 func (w *window) positionToOffset(pos event.Position) (int64, error) {
@@ -278,20 +344,62 @@ func (w *window) positionToOffset(pos event.Position) (int64, error) {
 			mathutil.MinInt64(pos.Offset, mathutil.MaxInt64(w.length, 1)-1-w.cursor),
 			-w.cursor,
 		), nil
+	case event.Pattern:
+		offset, err := w.findPattern([]byte(pos.Pattern), pos.Forward)
+		if err != nil {
+			return 0, err
+		}
+		return offset + mathutil.MaxInt64(
+			mathutil.MinInt64(pos.Offset, mathutil.MaxInt64(w.length, 1)-1-offset),
+			-offset,
+		), nil
+	case event.Mark:
+		offset, ok := w.buf.marks[pos.Name]
+		if !ok {
+			return 0, fmt.Errorf("mark not set: '%c", pos.Name)
+		}
+		return offset + mathutil.MaxInt64(
+			mathutil.MinInt64(pos.Offset, mathutil.MaxInt64(w.length, 1)-1-offset),
+			-offset,
+		), nil
 	default:
 		return 0, errors.New("invalid range")
 	}
 }
 
+func (w *window) evaluate(src string) (expr.Value, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return expr.Eval(src, w)
+}
+
+// Cursor implements expr.Env.
+func (w *window) Cursor() int64 {
+	return w.cursor
+}
+
+// Length implements expr.Env.
+func (w *window) Length() int64 {
+	return w.length
+}
+
+// ReadAt implements expr.Env.
+func (w *window) ReadAt(p []byte, offset int64) (int, error) {
+	return w.buf.buffer.ReadAt(p, offset)
+}
+
 func (w *window) state() (*state.WindowState, error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	w.sync()
 	n, bytes, err := w.readBytes(w.offset, int(w.height*w.width))
 	if err != nil {
 		return nil, err
 	}
 	return &state.WindowState{
-		Name:          w.name,
+		Name:          w.buf.name,
+		Modified:      w.buf.changedTick != w.buf.savedTick,
+		ReadOnly:      w.buf.options.readonly,
 		Width:         int(w.width),
 		Offset:        w.offset,
 		Cursor:        w.cursor,
@@ -301,45 +409,83 @@ func (w *window) state() (*state.WindowState, error) {
 		Pending:       w.pending,
 		PendingByte:   w.pendingByte,
 		VisualStart:   w.visualStart,
-		EditedIndices: w.buffer.EditedIndices(),
+		EditedIndices: w.buf.buffer.EditedIndices(),
 		FocusText:     w.focusText,
 	}, nil
 }
 
 func (w *window) insert(offset int64, c byte) {
-	w.buffer.Insert(offset, c)
+	w.buf.insert(offset, c)
 	w.changedTick++
+	w.syncedTick = w.buf.changedTick
 }
 
 func (w *window) replace(offset int64, c byte) {
-	w.buffer.Replace(offset, c)
+	w.buf.replace(offset, c)
 	w.changedTick++
+	w.syncedTick = w.buf.changedTick
 }
 
 func (w *window) delete(offset int64) {
-	w.buffer.Delete(offset)
+	w.buf.delete(offset)
 	w.changedTick++
+	w.syncedTick = w.buf.changedTick
+}
+
+// checkEdit returns an error if the event changes the buffer
+// which cannot be changed.
+func (w *window) checkEdit(e event.Event) error {
+	switch e.Type {
+	case event.DeleteByte, event.DeletePrevByte, event.Increment, event.Decrement,
+		event.Backspace, event.Delete, event.Undo, event.Redo:
+	case event.Rune:
+		if e.Mode != mode.Insert && e.Mode != mode.Replace {
+			return nil
+		}
+	default:
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.checkModifiable()
+}
+
+// replaceRange replaces the bytes in [from, to) with the bytes.
+func (w *window) replaceRange(from, to int64, bs []byte) {
+	n := mathutil.MinInt64(to-from, int64(len(bs)))
+	if n > 0 {
+		w.buf.replaceBytes(from, bs[:n])
+	}
+	if from+n < to {
+		w.buf.deleteBytes(from+n, to-from-n)
+	}
+	if n < int64(len(bs)) {
+		w.buf.insertBytes(from+n, bs[n:])
+	}
+	w.sync()
 }
 
 func (w *window) undo(count int64) {
 	for i := int64(0); i < mathutil.MaxInt64(count, 1); i++ {
-		buffer, _, offset, cursor := w.history.Undo()
-		if buffer == nil {
+		offset, cursor, ok := w.buf.undo()
+		if !ok {
 			return
 		}
-		w.buffer, w.offset, w.cursor = buffer, offset, cursor
-		w.length, _ = w.buffer.Len()
+		w.offset, w.cursor = offset, cursor
+		w.length, _ = w.buf.buffer.Len()
+		w.syncedTick = w.buf.changedTick
 	}
 }
 
 func (w *window) redo(count int64) {
 	for i := int64(0); i < mathutil.MaxInt64(count, 1); i++ {
-		buffer, offset, cursor := w.history.Redo()
-		if buffer == nil {
+		offset, cursor, ok := w.buf.redo()
+		if !ok {
 			return
 		}
-		w.buffer, w.offset, w.cursor = buffer, offset, cursor
-		w.length, _ = w.buffer.Len()
+		w.offset, w.cursor = offset, cursor
+		w.length, _ = w.buf.buffer.Len()
+		w.syncedTick = w.buf.changedTick
 	}
 }
 
@@ -566,6 +712,25 @@ func (w *window) jumpTo() {
 	if offset <= 0 || w.length <= offset {
 		return
 	}
+	w.jump(offset)
+}
+
+// jumpToPointer reads the integer under the cursor in the format of the
+// jumpformat option and jumps to the offset relative to the jumpbase option.
+func (w *window) jumpToPointer() error {
+	v, err := expr.ReadInt(w, w.options.jumpFormat, w.cursor)
+	if err != nil {
+		return err
+	}
+	offset := v - w.options.jumpBase
+	if offset < 0 || w.length <= offset {
+		return fmt.Errorf("pointer out of the buffer: 0x%x", v)
+	}
+	w.jump(offset)
+	return nil
+}
+
+func (w *window) jump(offset int64) {
 	w.stack = append(w.stack, position{w.cursor, w.offset})
 	w.cursor = offset
 	w.offset = mathutil.MaxInt64(offset-offset%w.width-mathutil.MaxInt64(w.height/3, 0)*w.width, 0)
@@ -580,6 +745,36 @@ func (w *window) jumpBack() {
 	w.stack = w.stack[:len(w.stack)-1]
 }
 
+func (w *window) setMark(name rune) {
+	if 'a' <= name && name <= 'z' {
+		w.buf.marks[name] = w.cursor
+	}
+}
+
+func (w *window) gotoMark(name rune) {
+	offset, ok := w.buf.marks[name]
+	if !ok {
+		return
+	}
+	w.stack = append(w.stack, position{w.cursor, w.offset})
+	w.cursorGotoPos(event.Absolute{Offset: offset})
+}
+
+func (w *window) listMarks() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	names := make([]rune, 0, len(w.buf.marks))
+	for name := range w.buf.marks {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	marks := make([]string, len(names))
+	for i, name := range names {
+		marks[i] = fmt.Sprintf("'%c %d (0x%x)", name, w.buf.marks[name], w.buf.marks[name])
+	}
+	return marks
+}
+
 func (w *window) deleteByte(count int64) {
 	if w.length == 0 {
 		return
@@ -825,6 +1020,43 @@ func (w *window) searchBackward(str string) {
 	}
 }
 
+const findChunkSize = 1 << 16
+
+// findPattern searches the entire buffer for the pattern. Forward search
+// starts at the cursor while backward search starts just before the cursor.
+func (w *window) findPattern(target []byte, forward bool) (int64, error) {
+	if len(target) == 0 {
+		return 0, errors.New("empty pattern")
+	}
+	size := mathutil.MaxInt(findChunkSize, 2*len(target))
+	if forward {
+		for base := w.cursor; base < w.length; base += int64(size - len(target) + 1) {
+			n, bs, err := w.readBytes(base, size)
+			if err != nil {
+				return 0, err
+			}
+			if i := bytes.Index(bs[:n], target); i >= 0 {
+				return base + int64(i), nil
+			}
+		}
+	} else {
+		for end := w.cursor - 1 + int64(len(target)); end > 0; end -= int64(size - len(target) + 1) {
+			base := mathutil.MaxInt64(end-int64(size), 0)
+			n, bs, err := w.readBytes(base, int(end-base))
+			if err != nil {
+				return 0, err
+			}
+			if i := bytes.LastIndex(bs[:n], target); i >= 0 {
+				return base + int64(i), nil
+			}
+			if base == 0 {
+				break
+			}
+		}
+	}
+	return 0, fmt.Errorf("pattern not found: %q", target)
+}
+
 func (w *window) close() {
 	close(w.eventCh)
 }
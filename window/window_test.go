@@ -1272,3 +1272,144 @@ func TestWindowWriteTo(t *testing.T) {
 		}
 	}
 }
+
+func TestWindowMarks(t *testing.T) {
+	r := strings.NewReader("Hello, world!")
+	window, err := newWindow(r, "test", "test", make(chan struct{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	window.setSize(16, 10)
+	window.cursorNext(mode.Normal, 7)
+	window.setMark('a')
+	window.cursorNext(mode.Normal, 4)
+	window.setMark('b')
+	window.pageTop()
+
+	window.gotoMark('a')
+	if window.cursor != 7 {
+		t.Errorf("window.cursor should be %d but got %d", 7, window.cursor)
+	}
+	window.gotoMark('c')
+	if window.cursor != 7 {
+		t.Errorf("window.cursor should be %d but got %d", 7, window.cursor)
+	}
+	window.jumpBack()
+	if window.cursor != 0 {
+		t.Errorf("window.cursor should be %d but got %d", 0, window.cursor)
+	}
+
+	window.insert(2, 'x')
+	window.insert(9, 'y')
+	window.delete(0)
+	window.length++
+	if window.buf.marks['a'] != 7 {
+		t.Errorf("mark a should be %d but got %d", 7, window.buf.marks['a'])
+	}
+	if window.buf.marks['b'] != 12 {
+		t.Errorf("mark b should be %d but got %d", 12, window.buf.marks['b'])
+	}
+
+	b := new(bytes.Buffer)
+	if _, err := window.writeTo(&event.Range{From: event.Mark{Name: 'a'}, To: event.Mark{Name: 'b'}}, b); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "wyorld"; b.String() != expected {
+		t.Errorf("window should write %q but got %q", expected, b.String())
+	}
+	if _, err := window.writeTo(&event.Range{From: event.Mark{Name: 'a'}, To: event.Mark{Name: 'z'}}, b); err == nil {
+		t.Errorf("err should not be nil for unset mark")
+	}
+
+	if expected := []string{"'a 7 (0x7)", "'b 12 (0xc)"}; !reflect.DeepEqual(window.listMarks(), expected) {
+		t.Errorf("listMarks should return %v but got %v", expected, window.listMarks())
+	}
+}
+
+func TestWindowFindPattern(t *testing.T) {
+	str := "MZ" + strings.Repeat("\x00", 70000) + "PE\x00\x00" + strings.Repeat("\x01", 70000) + "PE"
+	window, err := newWindow(strings.NewReader(str), "test", "test", make(chan struct{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	window.setSize(16, 10)
+	for _, testCase := range []struct {
+		r        *event.Range
+		expected string
+	}{
+		{&event.Range{From: event.Pattern{Pattern: "MZ", Forward: true}, To: event.Pattern{Pattern: "PE", Forward: true, Offset: -70000}}, "MZ\x00"},
+		{&event.Range{From: event.Pattern{Pattern: "PE", Forward: true}, To: event.Pattern{Pattern: "PE", Forward: true, Offset: 3}}, "PE\x00\x00"},
+	} {
+		b := new(bytes.Buffer)
+		if _, err := window.writeTo(testCase.r, b); err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if b.String() != testCase.expected {
+			t.Errorf("window should write %q with range %+v but got %q", testCase.expected, testCase.r, b.String())
+		}
+	}
+
+	window.cursorGotoPos(event.Absolute{Offset: 140006})
+	for _, testCase := range []struct {
+		pattern  string
+		forward  bool
+		expected int64
+	}{
+		{"PE", false, 70002},
+		{"\x01PE", false, 140005},
+		{"MZ", false, 0},
+		{"MZ", true, -1},
+		{"ZM", false, -1},
+	} {
+		offset, err := window.findPattern([]byte(testCase.pattern), testCase.forward)
+		if testCase.expected < 0 {
+			if err == nil {
+				t.Errorf("findPattern(%q) should return an error", testCase.pattern)
+			}
+		} else if offset != testCase.expected {
+			t.Errorf("findPattern(%q) should return %d but got %d (%v)", testCase.pattern, testCase.expected, offset, err)
+		}
+	}
+}
+
+func TestWindowJumpToPointer(t *testing.T) {
+	str := "\x10\x00\x00\x00" + strings.Repeat("\x00", 12) + "\x00\x40\x00\x18" + strings.Repeat("\x00", 4) + "\x00\x00\x40\x00"
+	window, err := newWindow(strings.NewReader(str), "test", "test", make(chan struct{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	window.setSize(16, 10)
+	if err := window.jumpToPointer(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if window.cursor != 16 {
+		t.Errorf("window.cursor should be %d but got %d", 16, window.cursor)
+	}
+	window.options.jumpFormat = "u32be"
+	if err := window.jumpToPointer(); err == nil || err.Error() != "pointer out of the buffer: 0x400018" {
+		t.Errorf("err should be %q but got: %v", "pointer out of the buffer: 0x400018", err)
+	}
+	window.options.jumpBase = 0x400000
+	if err := window.jumpToPointer(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if window.cursor != 24 {
+		t.Errorf("window.cursor should be %d but got %d", 24, window.cursor)
+	}
+	window.options.jumpFormat = "u64le"
+	if err := window.jumpToPointer(); err == nil || err.Error() != "u64le: cannot read 8 bytes at 0x18" {
+		t.Errorf("err should be %q but got: %v", "u64le: cannot read 8 bytes at 0x18", err)
+	}
+	if window.cursor != 24 {
+		t.Errorf("window.cursor should be %d but got %d", 24, window.cursor)
+	}
+	window.options.jumpFormat = "u24le"
+	if err := window.jumpToPointer(); err == nil || err.Error() != "unknown format: u24le" {
+		t.Errorf("err should be %q but got: %v", "unknown format: u24le", err)
+	}
+	window.jumpBack()
+	window.jumpBack()
+	if window.cursor != 0 {
+		t.Errorf("window.cursor should be %d but got %d", 0, window.cursor)
+	}
+}
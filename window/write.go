@@ -0,0 +1,164 @@
+package window
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/itchyny/bed/event"
+)
+
+// writeInPlace writes the buffer to the file, instead of renaming a temporary
+// file. When the buffer only replaces the bytes of the file, only the edited
+// regions are written. Otherwise, the whole buffer is written only when whole
+// is true, and the bool is false if not written. The original bytes of the
+// file are kept before writing, so that the undo history is not cleared as
+// with renaming. The file is copied before writing with the backup or
+// writebackup option.
+func (m *Manager) writeInPlace(window *window, whole, force bool) (int64, bool, error) {
+	b := window.buf
+	f, err := os.OpenFile(b.filename, os.O_WRONLY, 0)
+	if err != nil {
+		if !whole || os.IsNotExist(err) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	defer f.Close()
+	var r *fileReader
+	var same bool
+	for _, file := range m.files {
+		if file.name == b.filename {
+			r = file.file
+			if same, err = sameFile(r.File, f); err != nil {
+				return 0, false, err
+			}
+			break
+		}
+	}
+	window.mu.Lock()
+	changedTick := b.changedTick
+	window.mu.Unlock()
+	var n int64
+	var ok bool
+	var backup string
+	if same {
+		window.mu.Lock()
+		var eis []int64
+		if eis, ok = b.buffer.ReplacedIndices(r); ok {
+			if backup, err = m.backupForOverwrite(b.filename, force); err == nil {
+				if err = r.keep(eis); err == nil {
+					n, err = writeReplaced(b, eis, f)
+				}
+			}
+		}
+		window.mu.Unlock()
+		if err != nil {
+			return 0, false, err
+		}
+	}
+	if !ok {
+		if !whole {
+			return 0, false, nil
+		}
+		if backup, err = m.backupForOverwrite(b.filename, force); err != nil {
+			return 0, false, err
+		}
+		if same {
+			window.mu.Lock()
+			err = r.detach()
+			window.mu.Unlock()
+			if err != nil {
+				return 0, false, err
+			}
+		}
+		if n, err = window.writeTo(nil, f); err != nil {
+			return 0, false, err
+		}
+		if err := f.Truncate(n); err != nil {
+			return 0, false, err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return 0, false, err
+	}
+	m.removeBackup(backup)
+	window.mu.Lock()
+	b.savedTick = changedTick
+	b.swap.remove()
+	window.mu.Unlock()
+	b.updateStat()
+	return n, true, nil
+}
+
+// writeReplaced writes the edited regions of the buffer to the file,
+// where the buffer only replaces the bytes of the file.
+func writeReplaced(b *fileBuffer, eis []int64, f *os.File) (int64, error) {
+	for i := 0; i < len(eis); i += 2 {
+		bs := make([]byte, eis[i+1]-eis[i])
+		if _, err := b.buffer.ReadAt(bs, eis[i]); err != nil && err != io.EOF {
+			return 0, err
+		}
+		if _, err := f.WriteAt(bs, eis[i]); err != nil {
+			return 0, err
+		}
+	}
+	return b.buffer.Len()
+}
+
+func sameFile(f1, f2 *os.File) (bool, error) {
+	info1, err := f1.Stat()
+	if err != nil {
+		return false, err
+	}
+	info2, err := f2.Stat()
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(info1, info2), nil
+}
+
+// resolveSymlinks returns the path of the file the symbolic link points to.
+// The name is returned as it is if the file does not exist.
+func resolveSymlinks(name string) string {
+	if path, err := filepath.EvalSymlinks(name); err == nil {
+		return path
+	}
+	return name
+}
+
+// writeExisting overwrites the existing file, which is used when a temporary
+// file cannot be created in the directory.
+func (m *Manager) writeExisting(window *window, r *event.Range, path, filename string, force bool) (int64, error) {
+	if r == nil && filename == window.buf.filename {
+		n, _, err := m.writeInPlace(window, true, force)
+		return n, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	for _, file := range m.files {
+		if same, err := sameFile(file.file.File, f); err != nil || same {
+			return 0, fmt.Errorf("cannot overwrite %s in the read-only directory", path)
+		}
+	}
+	backup, err := m.backupForOverwrite(path, force)
+	if err != nil {
+		return 0, err
+	}
+	n, err := window.writeTo(r, f)
+	if err != nil {
+		return 0, err
+	}
+	if err := f.Truncate(n); err != nil {
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	m.removeBackup(backup)
+	return n, nil
+}